@@ -15,6 +15,8 @@ const (
 	IOCmdPwrite
 	IOCmdFSync
 	IOCmdFDSync
+	// 4 was the experimental IOCB_CMD_PREADX, it's never merged
+	_
 	IOCmdPoll
 	IOCmdNoop
 	IOCmdPreadv
@@ -15,6 +15,11 @@ func newAsyncIO(name string, opt Options) (*AsyncIO, error) {
 	return nil, errors.New("Please use AIO on linux")
 }
 
+func newAsyncIOFile(fd *os.File, opt Options) (*AsyncIO, error) {
+	fd.Close()
+	return nil, errors.New("Please use AIO on linux")
+}
+
 func (aio *AsyncIO) WriteAtv(bs [][]byte, off int64) (int, error) {
 	return 0, nil
 }
@@ -23,6 +28,42 @@ func (aio *AsyncIO) Append(bs [][]byte) (int, error) {
 	return 0, nil
 }
 
+func (aio *AsyncIO) Allocate(off, n int64, keepSize bool) error {
+	return ErrNotSupported
+}
+
+func (aio *AsyncIO) PunchHole(off, n int64) error {
+	return ErrNotSupported
+}
+
+func (aio *AsyncIO) ZeroRange(off, n int64) error {
+	return ErrNotSupported
+}
+
+func (aio *AsyncIO) CollapseRange(off, n int64) error {
+	return ErrNotSupported
+}
+
+func (aio *AsyncIO) InsertRange(off, n int64) error {
+	return ErrNotSupported
+}
+
+func (aio *AsyncIO) Extents() ([]Extent, error) {
+	return nil, ErrNotSupported
+}
+
+func (aio *AsyncIO) Statx() (*Statx, error) {
+	return nil, ErrNotSupported
+}
+
+func (aio *AsyncIO) DataSync() error {
+	return ErrNotSupported
+}
+
+func (aio *AsyncIO) SyncRange(off, n int64, flags SyncRangeFlag) error {
+	return ErrNotSupported
+}
+
 func (aio *AsyncIO) FLock() error {
 	return nil
 }
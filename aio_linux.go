@@ -5,6 +5,7 @@ package ioengine
 import (
 	"errors"
 	"fmt"
+	"math"
 	"os"
 	"sync"
 	"unsafe"
@@ -32,13 +33,21 @@ type runningEvent struct {
 	wrote uint
 	iocb  *iocb
 	reqID RequestID
+
+	// write the file range [start, end) of the write request, it's empty for read
+	write span
+
+	// state the state of the request, it's used to wait for the request
+	state *requestState
 }
 
 type requestState struct {
 	iocb  *iocb
-	done  bool
 	err   error
 	bytes int64
+
+	// finished it's closed when the request is done
+	finished chan struct{}
 }
 
 // AsyncIO async IO
@@ -96,6 +105,11 @@ func newAsyncIO(name string, opt Options) (*AsyncIO, error) {
 		return nil, err
 	}
 
+	return newAsyncIOFile(fd, opt)
+}
+
+// newAsyncIOFile submits the IO to the file opened by the direct IO flags, the file is closed if it fails.
+func newAsyncIOFile(fd *os.File, opt Options) (*AsyncIO, error) {
 	// verify aio queue depth
 	if opt.AIOQueueDepth <= 0 || opt.AIOQueueDepth > defaultQueueDepth {
 		opt.AIOQueueDepth = defaultQueueDepth
@@ -124,6 +138,7 @@ func newAsyncIO(name string, opt Options) (*AsyncIO, error) {
 	}
 
 	aio := &AsyncIO{
+		opt:       opt,
 		fd:        fd,
 		ioctx:     ioctx,
 		iocbs:     iocbs,
@@ -291,20 +306,13 @@ func (aio *AsyncIO) freeEvent(re *runningEvent, iocb *iocb, err error) error {
 	// put the iocb back into the available pool
 	aio.available.Set(pointer2string(unsafe.Pointer(re.iocb)), re.iocb)
 
-	// update the stat in request pool
-	ri, ok := aio.request.Get(int2string(int64(re.reqID)))
-	if !ok {
-		return ErrReqIDNotFound
-	}
-	r, ok := ri.(*requestState)
-	if !ok {
-		return ErrReqIDNotFound
-	}
-	r.done = true
+	// update the stat of the request, it's published to the waiters by closing finished
+	r := re.state
 	r.bytes = int64(re.wrote)
 	if err != nil {
 		r.err = err
 	}
+	close(r.finished)
 
 	return nil
 }
@@ -331,48 +339,59 @@ func (aio *AsyncIO) waitAll() {
 
 // WaitFor will block until the given RequestId is done
 func (aio *AsyncIO) WaitFor(id RequestID) (int, error) {
-	for {
-		// check if its ready
-		done, err := aio.IsDone(id)
-		if err != nil {
-			return 0, err
-		}
-		if done {
-			break
-		}
+	r, err := aio.requestState(id)
+	if err != nil {
+		return 0, err
 	}
+	<-r.finished
 
 	return aio.ack(id)
 }
 
 func (aio *AsyncIO) IsDone(id RequestID) (bool, error) {
-	v, ok := aio.request.Get(int2string(int64(id)))
-	if !ok {
-		return false, ErrReqIDNotFound
-	}
-	r, ok := v.(*requestState)
-	if !ok {
-		return false, ErrReqIDNotFound
+	r, err := aio.requestState(id)
+	if err != nil {
+		return false, err
 	}
-	return r.done, nil
+	return r.isDone(), nil
 }
 
 // Ack acknowledges that we have accepted a finished result ID
 // if the request is not done, an error is returned
 func (aio *AsyncIO) ack(id RequestID) (int, error) {
+	r, err := aio.requestState(id)
+	if err != nil {
+		return 0, err
+	}
+	if r.isDone() {
+		aio.request.Remove(int2string(int64(id)))
+		return int(r.bytes), r.err
+	}
+	return 0, ErrNotDone
+}
+
+// requestState returns the state of the request in request pool.
+func (aio *AsyncIO) requestState(id RequestID) (*requestState, error) {
 	v, ok := aio.request.Get(int2string(int64(id)))
 	if !ok {
-		return 0, ErrReqIDNotFound
+		return nil, ErrReqIDNotFound
 	}
 	r, ok := v.(*requestState)
 	if !ok {
-		return 0, ErrReqIDNotFound
+		return nil, ErrReqIDNotFound
 	}
-	if r.done {
-		aio.request.Remove(int2string(int64(id)))
-		return int(r.bytes), r.err
+	return r, nil
+}
+
+// isDone reports whether the request is done, the state written by freeEvent
+// is visible once finished is closed.
+func (r *requestState) isDone() bool {
+	select {
+	case <-r.finished:
+		return true
+	default:
+		return false
 	}
-	return 0, ErrNotDone
 }
 
 func (aio *AsyncIO) reCalcEnd(offset int64) {
@@ -475,16 +494,20 @@ func (aio *AsyncIO) submitIO(cmd IocbCmd, bs [][]byte, offset int64) (RequestID,
 	}
 	aio.Unlock()
 
+	rs := &requestState{
+		iocb:     nIocb,
+		finished: make(chan struct{}),
+	}
+
 	re := &runningEvent{
 		// this prevents the gc from collecting the buffer
 		data:  bs,
 		iocb:  nIocb,
 		reqID: id,
+		state: rs,
 	}
-
-	rs := &requestState{
-		iocb: nIocb,
-		done: false,
+	if write {
+		re.write = span{offset, offset + int64(count(bs))}
 	}
 
 	// add the iocb to the running event pool
@@ -509,7 +532,13 @@ func (aio *AsyncIO) Append(bs [][]byte) (int, error) {
 	return aio.WaitFor(id)
 }
 
+// Seek sets the offset for the next Read or Write, SeekData and SeekHole
+// will wait for all submitted jobs to finish then seek by the file.
 func (aio *AsyncIO) Seek(offset int64, whence int) (int64, error) {
+	if whence == SeekData || whence == SeekHole {
+		aio.waitAll()
+	}
+
 	aio.Lock()
 	defer aio.Unlock()
 
@@ -521,6 +550,14 @@ func (aio *AsyncIO) Seek(offset int64, whence int) (int64, error) {
 		nOffset = int64(aio.offset) + offset
 	case 2:
 		nOffset = int64(aio.end) + offset
+	case SeekData, SeekHole:
+		n, err := aio.fd.Seek(offset, whence)
+		if err != nil {
+			return 0, err
+		}
+		nOffset = n
+	default:
+		return 0, errors.New("aio: invalid whence")
 	}
 
 	aio.offset = nOffset
@@ -536,6 +573,77 @@ func (aio *AsyncIO) Truncate(size int64) error {
 	return aio.fd.Truncate(size)
 }
 
+// Allocate will wait for all submitted jobs to finish
+// then preallocate the disk space of the range [off, off+n).
+func (aio *AsyncIO) Allocate(off, n int64, keepSize bool) error {
+	return aio.modify(func() error {
+		return allocate(aio.fd, off, n, keepSize)
+	})
+}
+
+// PunchHole will wait for all submitted jobs to finish
+// then deallocate the disk space of the range [off, off+n).
+func (aio *AsyncIO) PunchHole(off, n int64) error {
+	return aio.modify(func() error {
+		return punchHole(aio.fd, off, n)
+	})
+}
+
+// ZeroRange will wait for all submitted jobs to finish then zero the range [off, off+n).
+func (aio *AsyncIO) ZeroRange(off, n int64) error {
+	return aio.modify(func() error {
+		return zeroRange(aio.fd, off, n)
+	})
+}
+
+// CollapseRange will wait for all submitted jobs to finish
+// then remove the range [off, off+n) without leaving a hole.
+func (aio *AsyncIO) CollapseRange(off, n int64) error {
+	return aio.modify(func() error {
+		return collapseRange(aio.fd, off, n)
+	})
+}
+
+// InsertRange will wait for all submitted jobs to finish
+// then insert a hole of n bytes at off.
+func (aio *AsyncIO) InsertRange(off, n int64) error {
+	return aio.modify(func() error {
+		return insertRange(aio.fd, off, n)
+	})
+}
+
+// modify waits for all submitted jobs to finish, the data can't be
+// shifted underneath the running IO, then runs fn and refreshes the end of file.
+func (aio *AsyncIO) modify(fn func() error) error {
+	aio.waitAll()
+
+	// fn may change the file partially even if it fails
+	ferr := fn()
+	stat, err := aio.fd.Stat()
+	if err != nil {
+		return err
+	}
+	aio.Lock()
+	aio.end = stat.Size()
+	aio.Unlock()
+
+	return ferr
+}
+
+// Extents will wait for all submitted jobs to finish
+// then return the physical extents of the file.
+func (aio *AsyncIO) Extents() ([]Extent, error) {
+	aio.waitAll()
+	return fiemap(aio.fd)
+}
+
+// Statx will wait for all submitted jobs to finish
+// then return the extended status of the file.
+func (aio *AsyncIO) Statx() (*Statx, error) {
+	aio.waitAll()
+	return statx(aio.fd)
+}
+
 // Sync will wait for all submitted jobs to finish and then sync
 // the file descriptor.  Because the Linux kernel does not actually
 // support Sync via the AIO interface we just issue a plain old sync
@@ -547,6 +655,45 @@ func (aio *AsyncIO) Sync() error {
 	return aio.fd.Sync()
 }
 
+// DataSync will wait for all submitted jobs to finish and then
+// flush the file data and the metadata required to retrieve it by fdatasync.
+func (aio *AsyncIO) DataSync() error {
+	aio.waitAll()
+	return fdatasync(aio.fd)
+}
+
+// SyncRange will block until the submitted writes overlapping the range [off, off+n)
+// are done, the others keep running, and then write back the range by sync_file_range.
+// the overlapping writes aren't acked, their results are still returned by WaitFor.
+func (aio *AsyncIO) SyncRange(off, n int64, flags SyncRangeFlag) error {
+	if off < 0 || n < 0 {
+		return errors.New("aio: invalid range")
+	}
+	end := int64(math.MaxInt64)
+	if n > 0 && off+n > off {
+		end = off + n
+	}
+	for _, r := range aio.overlapped(off, end) {
+		<-r.finished
+	}
+	return syncFileRange(aio.fd, off, n, flags)
+}
+
+// overlapped returns the requests of the running writes overlapping the file range [start, end).
+func (aio *AsyncIO) overlapped(start, end int64) []*requestState {
+	var states []*requestState
+	for item := range aio.running.IterBuffered() {
+		re, ok := item.Val.(*runningEvent)
+		if !ok {
+			continue
+		}
+		if re.write.start < end && re.write.end > start {
+			states = append(states, re.state)
+		}
+	}
+	return states
+}
+
 // FLock async IO not impl Flock
 func (aio *AsyncIO) FLock() error {
 	return nil
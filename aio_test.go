@@ -27,7 +27,7 @@ func TestAIOStructure(t *testing.T) {
 		t.Fatal(fmt.Sprintf("Invalid iocb structure size: %d != %d", unsafe.Sizeof(cb), 64))
 	}
 	if unsafe.Sizeof(evt) != 32 {
-		t.Fatal(fmt.Sprintf("Invalid event structure size", unsafe.Sizeof(evt), 32))
+		t.Fatal(fmt.Sprintf("Invalid event structure size: %d != %d", unsafe.Sizeof(evt), 32))
 	}
 }
 
@@ -93,6 +93,36 @@ func TestAIOWriteAt(t *testing.T) {
 	}
 }
 
+func TestAIOWriteAtv(t *testing.T) {
+	fd, err := NewAsyncIO()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fd.Close()
+
+	b, err := MemAlign(2 * BlockSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	copy(b, []byte("hello world"))
+
+	nw, err := fd.WriteAtv([][]byte{b[:BlockSize], b[BlockSize:]}, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nw != len(b) {
+		t.Fatal("writeAtv: short write")
+	}
+
+	fi, err := fd.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Size() != int64(len(b)) {
+		t.Fatal("writeAtv: invalid file length")
+	}
+}
+
 func TestAIORead(t *testing.T) {
 	fd, err := NewAsyncIO()
 	if err != nil {
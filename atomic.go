@@ -0,0 +1,161 @@
+package ioengine
+
+import (
+	"errors"
+	"path/filepath"
+)
+
+// ErrAtomicWriterClosed write to a committed or closed AtomicWriter
+var ErrAtomicWriterClosed = errors.New("atomic writer: closed")
+
+// atomicTempHook wraps the temporary file of AtomicWriter, it's used to inject faults by tests.
+var atomicTempHook func(File) File
+
+// AtomicWriter writes a new version of the file which replaces it atomically by Commit,
+// the readers see either the old or the new content, never a partial one.
+// the content is written to a temporary file in the same directory, Commit syncs it,
+// renames it to the name and syncs the directory, so that the new content is durable
+// once Commit returns. the temporary file is removed if it fails or Close is called
+// before Commit, on linux it's an unnamed O_TMPFILE, a crashed writer leaves nothing behind.
+// on DIO and AIO mode, the writes are buffered by DirectWriter.
+// AtomicWriter is not safe for concurrent use.
+type AtomicWriter struct {
+	name string
+	tf   *TempFile
+
+	// dw buffers the writes on DIO and AIO mode
+	dw *DirectWriter
+
+	// off the file offset of the next write on the other modes
+	off int64
+
+	// lock the lock of name if opt.FileLock is set, it's held until Commit or Close
+	lock *FileLock
+
+	err error
+}
+
+// NewAtomicWriter returns an AtomicWriter replacing name through the IO engine of opt.
+// if opt.FileLock is ReadWrite, the exclusive lock of name is held until Commit or Close,
+// so the writers of the same name are serialized.
+func NewAtomicWriter(name string, opt Options) (*AtomicWriter, error) {
+	w := &AtomicWriter{name: name}
+
+	if opt.FileLock != None {
+		lock, err := NewFileLock(name, opt.FileLock == ReadWrite)
+		if err != nil {
+			return nil, err
+		}
+		if err := lock.FLock(); err != nil {
+			return nil, err
+		}
+		w.lock = lock
+	}
+
+	tf, err := CreateTemp(filepath.Dir(name), opt)
+	if err != nil {
+		w.unlock()
+		return nil, err
+	}
+	if atomicTempHook != nil {
+		tf.File = atomicTempHook(tf.File)
+	}
+	w.tf = tf
+
+	if isDirect(tf) {
+		if w.dw, err = NewDirectWriter(tf, 0); err != nil {
+			w.abort()
+			return nil, err
+		}
+	}
+	return w, nil
+}
+
+// Write writes b to the temporary file, a failed write fails the following writes and Commit.
+func (w *AtomicWriter) Write(b []byte) (n int, err error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+
+	if w.dw != nil {
+		n, err = w.dw.Write(b)
+	} else {
+		n, err = w.tf.WriteAt(b, w.off)
+		w.off += int64(n)
+	}
+	if err != nil {
+		w.err = err
+	}
+	return n, err
+}
+
+// Commit syncs the temporary file, renames it to the name and syncs the directory.
+// the temporary file is removed if it fails before the rename.
+func (w *AtomicWriter) Commit() error {
+	if w.err != nil {
+		return w.err
+	}
+
+	if err := w.commit(); err != nil {
+		w.abort()
+		w.err = err
+		return err
+	}
+
+	w.err = ErrAtomicWriterClosed
+	err := w.tf.Close()
+	w.unlock()
+	if derr := syncDir(filepath.Dir(w.name)); derr != nil {
+		return derr
+	}
+	return err
+}
+
+func (w *AtomicWriter) commit() error {
+	if w.dw != nil {
+		if err := w.dw.Close(); err != nil {
+			return err
+		}
+	}
+	if err := w.tf.Sync(); err != nil {
+		return err
+	}
+	return w.tf.Publish(w.name)
+}
+
+// Close discards the written content if it isn't committed, the name isn't changed.
+func (w *AtomicWriter) Close() error {
+	if w.err == ErrAtomicWriterClosed {
+		return nil
+	}
+	w.err = ErrAtomicWriterClosed
+	return w.abort()
+}
+
+// abort closes and removes the temporary file and releases the lock.
+func (w *AtomicWriter) abort() error {
+	err := w.tf.Close()
+	w.unlock()
+	return err
+}
+
+func (w *AtomicWriter) unlock() {
+	if w.lock != nil {
+		w.lock.FUnlock()
+		w.lock = nil
+	}
+}
+
+// WriteFileAtomic replaces the content of name by data atomically through the IO engine of opt,
+// the new content is durable once it returns, see AtomicWriter.
+func WriteFileAtomic(name string, data []byte, opt Options) error {
+	w, err := NewAtomicWriter(name, opt)
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(data); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Commit()
+}
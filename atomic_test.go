@@ -0,0 +1,155 @@
+// +build linux
+
+package ioengine
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var errInjectedFault = errors.New("injected fault")
+
+// faultFile fails the writes beyond budget bytes and the syncs.
+type faultFile struct {
+	File
+	budget   int
+	failSync bool
+}
+
+func (f *faultFile) WriteAt(b []byte, off int64) (int, error) {
+	if len(b) <= f.budget {
+		f.budget -= len(b)
+		return f.File.WriteAt(b, off)
+	}
+	n, _ := f.File.WriteAt(b[:f.budget], off)
+	f.budget = 0
+	return n, errInjectedFault
+}
+
+func (f *faultFile) WriteAtv(bs [][]byte, off int64) (int, error) {
+	var b []byte
+	for _, v := range bs {
+		b = append(b, v...)
+	}
+	if len(b) <= f.budget {
+		f.budget -= len(b)
+		return f.File.WriteAtv(bs, off)
+	}
+	f.budget = 0
+	return 0, errInjectedFault
+}
+
+func (f *faultFile) Sync() error {
+	if f.failSync {
+		return errInjectedFault
+	}
+	return f.File.Sync()
+}
+
+func atomicTestDir(t *testing.T) (string, string) {
+	dir, err := ioutil.TempDir("/tmp/standardio", "atomic")
+	if err != nil {
+		t.Fatal(err)
+	}
+	name := filepath.Join(dir, "data")
+	if err := ioutil.WriteFile(name, []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return dir, name
+}
+
+func checkAtomicFile(t *testing.T, dir, name string, data []byte) {
+	got, err := ioutil.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, data) {
+		t.Fatalf("unmatched content %d bytes != %d bytes", len(got), len(data))
+	}
+	names, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, fi := range names {
+		if fi.Name() != "data" && fi.Name() != ".data-flock" {
+			t.Fatalf("the temporary file %s is left behind", fi.Name())
+		}
+	}
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	mmapOpt := DefaultOptions
+	mmapOpt.IOEngine = MMap
+	mmapOpt.MmapSize = BlockSize
+	mmapOpt.MmapWritable = true
+	dioOpt := DefaultOptions
+	dioOpt.IOEngine = DIO
+	aioOpt := DefaultOptions
+	aioOpt.IOEngine = AIO
+	lockOpt := DefaultOptions
+	lockOpt.FileLock = ReadWrite
+
+	data := bytes.Repeat([]byte("atomic"), 1000)
+	for _, opt := range []Options{DefaultOptions, mmapOpt, dioOpt, aioOpt, lockOpt} {
+		dir, name := atomicTestDir(t)
+		if err := WriteFileAtomic(name, data, opt); err != nil {
+			t.Fatalf("engine %d: %v", opt.IOEngine, err)
+		}
+		checkAtomicFile(t, dir, name, data)
+		os.RemoveAll(dir)
+	}
+}
+
+func TestAtomicWriterFault(t *testing.T) {
+	defer func() {
+		atomicTempHook = nil
+	}()
+
+	dioOpt := DefaultOptions
+	dioOpt.IOEngine = DIO
+	data := bytes.Repeat([]byte("atomic"), 2*BlockSize)
+	for _, opt := range []Options{DefaultOptions, dioOpt} {
+		for _, fault := range []faultFile{{budget: BlockSize}, {budget: len(data) * 2, failSync: true}} {
+			fault := fault
+			atomicTempHook = func(f File) File {
+				fault.File = f
+				return &fault
+			}
+
+			dir, name := atomicTestDir(t)
+			err := WriteFileAtomic(name, data, opt)
+			if err != errInjectedFault {
+				t.Fatalf("unexpected error %v", err)
+			}
+			checkAtomicFile(t, dir, name, []byte("old"))
+			os.RemoveAll(dir)
+		}
+	}
+}
+
+func TestAtomicWriterCrash(t *testing.T) {
+	dir, name := atomicTestDir(t)
+	defer os.RemoveAll(dir)
+
+	w, err := NewAtomicWriter(name, DefaultOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte("partial")); err != nil {
+		t.Fatal(err)
+	}
+	// the writer crashes before Commit, the unnamed temporary file is invisible
+	checkAtomicFile(t, dir, name, []byte("old"))
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Commit(); err != ErrAtomicWriterClosed {
+		t.Fatal("the closed writer shouldn't be committed")
+	}
+	checkAtomicFile(t, dir, name, []byte("old"))
+}
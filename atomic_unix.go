@@ -0,0 +1,15 @@
+// +build linux darwin
+
+package ioengine
+
+import "os"
+
+// syncDir commits the directory entries of dir, eg: the renamed file, to stable storage.
+func syncDir(dir string) error {
+	fd, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer fd.Close()
+	return fd.Sync()
+}
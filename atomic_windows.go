@@ -0,0 +1,8 @@
+// +build windows
+
+package ioengine
+
+// syncDir windows can't open the directory to sync it, it's skipped.
+func syncDir(dir string) error {
+	return nil
+}
@@ -0,0 +1,199 @@
+package ioengine
+
+import (
+	"errors"
+	"io"
+)
+
+// copyChunk the max bytes copied by once call of ReadAt and WriteAt in the copy loop.
+const copyChunk = 256 * BlockSize
+
+// modifier is impled by the engines which cache the file size,
+// modify runs fn which changes the file by its fd, then refreshes the size.
+type modifier interface {
+	modify(fn func() error) error
+}
+
+// unwrapper is impled by the wrappers of File, eg: SyncedFile and TempFile,
+// Unwrap returns the wrapped File.
+type unwrapper interface {
+	Unwrap() File
+}
+
+// dirtier is impled by the wrappers which track the written bytes, eg: SyncedFile,
+// dirty runs fn which changes up to n bytes of the wrapped File, then counts them as written.
+type dirtier interface {
+	dirty(n int64, fn func() error) error
+}
+
+// modifyFile runs fn which changes up to n bytes of f by its fd underneath the engine,
+// the wrappers are unwrapped until the engine which caches the file size.
+func modifyFile(f File, n int64, fn func() error) error {
+	if m, ok := f.(modifier); ok {
+		return m.modify(fn)
+	}
+	u, ok := f.(unwrapper)
+	if !ok {
+		return fn()
+	}
+
+	modify := func() error {
+		return modifyFile(u.Unwrap(), n, fn)
+	}
+	if d, ok := f.(dirtier); ok {
+		return d.dirty(n, modify)
+	}
+	return modify()
+}
+
+// CopyRange copies n bytes from src at srcOff to dst at dstOff by copy_file_range,
+// the data is copied in the kernel without passing through the user space.
+// If the kernel refuses, eg: across file systems, it falls back to a copy loop
+// through the engines, the offsets and n must be a multiple of BlockSize
+// if either File is opened by DIO or AIO, except that n can be unaligned
+// when the range reaches the end of dst.
+// It returns the number of bytes copied, it's less than n if src ends.
+func CopyRange(dst File, dstOff int64, src File, srcOff, n int64) (int64, error) {
+	if dstOff < 0 || srcOff < 0 || n < 0 {
+		return 0, errors.New("copy: invalid range")
+	}
+
+	var copied int64
+	err := modifyFile(dst, n, func() error {
+		var err error
+		copied, err = copyFileRange(dst, dstOff, src, srcOff, n)
+		return err
+	})
+	if err != ErrNotSupported {
+		return copied, err
+	}
+
+	nc, err := copyLoop(dst, dstOff+copied, src, srcOff+copied, n-copied)
+	return copied + nc, err
+}
+
+// Clone makes dst a copy-on-write clone of the whole src by FICLONE,
+// the data blocks are shared until either file is modified, eg: on XFS and Btrfs.
+// If the file system refuses, it falls back to CopyRange and truncates dst to the size of src.
+func Clone(dst, src File) error {
+	stat, err := src.Stat()
+	if err != nil {
+		return err
+	}
+
+	err = modifyFile(dst, stat.Size(), func() error {
+		return ficlone(dst, src)
+	})
+	if err != ErrNotSupported {
+		return err
+	}
+
+	if err := dst.Truncate(0); err != nil {
+		return err
+	}
+	if _, err := CopyRange(dst, 0, src, 0, stat.Size()); err != nil {
+		return err
+	}
+	return dst.Truncate(stat.Size())
+}
+
+// CloneRange clones n bytes from src at srcOff to dst at dstOff by FICLONERANGE,
+// the offsets and n must be a multiple of the file system block size,
+// n can be unaligned if the range reaches the end of src.
+// If the file system refuses, it falls back to CopyRange.
+func CloneRange(dst File, dstOff int64, src File, srcOff, n int64) error {
+	if dstOff < 0 || srcOff < 0 || n < 0 {
+		return errors.New("copy: invalid range")
+	}
+
+	err := modifyFile(dst, n, func() error {
+		return ficloneRange(dst, dstOff, src, srcOff, n)
+	})
+	if err != ErrNotSupported {
+		return err
+	}
+
+	_, err = CopyRange(dst, dstOff, src, srcOff, n)
+	return err
+}
+
+// isDirect returns whether the File requires the aligned IO.
+func isDirect(f File) bool {
+	engine := f.Option().IOEngine
+	return engine == DIO || engine == AIO
+}
+
+// copyLoop copies n bytes from src at srcOff to dst at dstOff through an aligned buffer,
+// the unaligned tail written by DIO is padded and dst is truncated back.
+func copyLoop(dst File, dstOff int64, src File, srcOff, n int64) (int64, error) {
+	if n == 0 {
+		return 0, nil
+	}
+
+	stat, err := dst.Stat()
+	if err != nil {
+		return 0, err
+	}
+	end := dstOff + n
+	if isDirect(src) || isDirect(dst) {
+		aligned := dstOff%BlockSize == 0 && srcOff%BlockSize == 0
+		if !aligned || (n%BlockSize != 0 && end < stat.Size()) {
+			return 0, errors.New("copy: unaligned range for direct IO")
+		}
+	}
+
+	buf, err := MemAlign(copyChunk)
+	if err != nil {
+		return 0, err
+	}
+
+	var copied int64
+	for copied < n {
+		size := n - copied
+		if size > copyChunk {
+			size = copyChunk
+		}
+		// the unaligned tail is read and written by the whole block
+		rsize := (size + BlockSize - 1) &^ (BlockSize - 1)
+
+		nr, err := src.ReadAt(buf[:rsize], srcOff+copied)
+		if err != nil && err != io.EOF {
+			return copied, err
+		}
+		if int64(nr) > size {
+			nr = int(size)
+		}
+		if nr == 0 {
+			break
+		}
+
+		wsize := int64(nr)
+		if isDirect(dst) {
+			wsize = (wsize + BlockSize - 1) &^ (BlockSize - 1)
+			for i := nr; i < int(wsize); i++ {
+				buf[i] = 0
+			}
+		}
+		if _, err := dst.WriteAt(buf[:wsize], dstOff+copied); err != nil {
+			return copied, err
+		}
+
+		copied += int64(nr)
+		if int64(nr) < size {
+			break
+		}
+	}
+
+	// truncate the padding of the tail block
+	if isDirect(dst) && copied%BlockSize != 0 {
+		size := dstOff + copied
+		if size < stat.Size() {
+			size = stat.Size()
+		}
+		if err := dst.Truncate(size); err != nil {
+			return copied, err
+		}
+	}
+
+	return copied, nil
+}
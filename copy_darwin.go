@@ -0,0 +1,18 @@
+// +build darwin
+
+package ioengine
+
+// copyFileRange darwin has no copy_file_range, the copy loop is used.
+func copyFileRange(dst File, dstOff int64, src File, srcOff, n int64) (int64, error) {
+	return 0, ErrNotSupported
+}
+
+// ficlone darwin can't clone into an opened file, the copy loop is used.
+func ficlone(dst, src File) error {
+	return ErrNotSupported
+}
+
+// ficloneRange darwin can't clone into an opened file, the copy loop is used.
+func ficloneRange(dst File, dstOff int64, src File, srcOff, n int64) error {
+	return ErrNotSupported
+}
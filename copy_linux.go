@@ -0,0 +1,97 @@
+// +build linux
+
+package ioengine
+
+import (
+	"os"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// ficloneIoctl FICLONE _IOW(0x94, 9, int)
+	ficloneIoctl = 0x40049409
+
+	// ficloneRangeIoctl FICLONERANGE _IOW(0x94, 13, struct file_clone_range)
+	ficloneRangeIoctl = 0x4020940d
+)
+
+// fileCloneRange linux struct file_clone_range
+type fileCloneRange struct {
+	srcFd     int64
+	srcOffset uint64
+	srcLength uint64
+	dstOffset uint64
+}
+
+// isRefused returns whether the kernel or file system refuses the copy or clone,
+// the caller should fall back to the copy loop.
+func isRefused(err error) bool {
+	switch err {
+	case unix.EXDEV, unix.ENOSYS, unix.EOPNOTSUPP, unix.ENOTTY:
+		return true
+	}
+	return false
+}
+
+// copyFileRange copies by copy_file_range until n bytes are copied or src ends,
+// it returns ErrNotSupported and the bytes copied if the kernel refuses.
+func copyFileRange(dst File, dstOff int64, src File, srcOff, n int64) (int64, error) {
+	var copied int64
+	for copied < n {
+		roff := srcOff + copied
+		woff := dstOff + copied
+		size := n - copied
+		if size > 1<<30 {
+			size = 1 << 30
+		}
+
+		nc, err := unix.CopyFileRange(int(src.Fd()), &roff, int(dst.Fd()), &woff, int(size), 0)
+		if err == unix.EINTR {
+			continue
+		}
+		if err != nil {
+			if isRefused(err) {
+				return copied, ErrNotSupported
+			}
+			return copied, os.NewSyscallError("COPY_FILE_RANGE", err)
+		}
+		if nc == 0 {
+			break
+		}
+		copied += int64(nc)
+	}
+
+	return copied, nil
+}
+
+// ficlone clones the whole src into dst by FICLONE ioctl.
+func ficlone(dst, src File) error {
+	_, _, errno := unix.Syscall(unix.SYS_IOCTL, dst.Fd(), ficloneIoctl, src.Fd())
+	if errno != 0 {
+		if isRefused(errno) {
+			return ErrNotSupported
+		}
+		return os.NewSyscallError("FICLONE", errno)
+	}
+	return nil
+}
+
+// ficloneRange clones the range of src into dst by FICLONERANGE ioctl.
+func ficloneRange(dst File, dstOff int64, src File, srcOff, n int64) error {
+	arg := fileCloneRange{
+		srcFd:     int64(src.Fd()),
+		srcOffset: uint64(srcOff),
+		srcLength: uint64(n),
+		dstOffset: uint64(dstOff),
+	}
+	_, _, errno := unix.Syscall(unix.SYS_IOCTL, dst.Fd(), ficloneRangeIoctl, uintptr(unsafe.Pointer(&arg)))
+	if errno != 0 {
+		if isRefused(errno) {
+			return ErrNotSupported
+		}
+		return os.NewSyscallError("FICLONERANGE", errno)
+	}
+	return nil
+}
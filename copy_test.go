@@ -0,0 +1,109 @@
+package ioengine
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+)
+
+func testCopyContent(t *testing.T, f File, off int64, expect []byte) {
+	b, err := MemAlign(uint(len(expect)+BlockSize-1) &^ (BlockSize - 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	nr, err := f.ReadAt(b, off)
+	if err != nil && err != io.EOF {
+		t.Fatal(err)
+	}
+	if nr < len(expect) || !bytes.Equal(b[:len(expect)], expect) {
+		t.Fatal("unmatched content")
+	}
+}
+
+func TestCopyRange(t *testing.T) {
+	src, err := NewFileIO()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+
+	content := bytes.Repeat([]byte("0123456789abcdef"), 3*BlockSize/16+100)
+	if err := src.Truncate(0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := src.WriteAt(content, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	dst, err := NewFileIO()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dst.Close()
+	if err := dst.Truncate(0); err != nil {
+		t.Fatal(err)
+	}
+	nc, err := CopyRange(dst, 10, src, 0, int64(len(content))+100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nc != int64(len(content)) {
+		t.Fatalf("copy range: %d", nc)
+	}
+	testCopyContent(t, dst, 10, content)
+
+	// the copy loop with DIO
+	dio, err := NewDirectIO()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dio.Close()
+	if err := dio.Truncate(0); err != nil {
+		t.Fatal(err)
+	}
+	nc, err = copyLoop(dio, 0, src, 0, int64(len(content)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stat, err := dio.Stat(); err != nil || nc != int64(len(content)) || stat.Size() != nc {
+		t.Fatalf("copy loop: %d, %v", nc, err)
+	}
+	testCopyContent(t, dio, 0, content)
+	if _, err := copyLoop(dio, 1, src, 0, BlockSize); err == nil {
+		t.Fatal("unaligned copy to DIO should fail")
+	}
+
+	// the mapping is refreshed after the clone
+	mmap, err := NewMemoryMap()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mmap.Close()
+	if err := Clone(mmap, src); err != nil {
+		t.Fatal(err)
+	}
+	if mmap.fileSize() != int64(len(content)) {
+		t.Fatalf("clone: unmatched mapping size %d", mmap.fileSize())
+	}
+	testCopyContent(t, mmap, 0, content)
+
+	if err := CloneRange(mmap, BlockSize, src, 0, BlockSize); err != nil {
+		t.Fatal(err)
+	}
+	testCopyContent(t, mmap, BlockSize, content[:BlockSize])
+
+	// the mapping is refreshed through the wrappers
+	opt := DefaultOptions
+	opt.IOEngine = MMap
+	opt.SyncPolicy = SyncPolicy{Interval: time.Hour}
+	tf, err := CreateTemp("/tmp/mmap", opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tf.Close()
+	if nc, err := CopyRange(tf, 0, src, 0, int64(len(content))); err != nil || nc != int64(len(content)) {
+		t.Fatalf("copy range through the wrappers: %d, %v", nc, err)
+	}
+	testCopyContent(t, tf, 0, content)
+}
@@ -0,0 +1,23 @@
+package ioengine
+
+// DataSync flushes the file data and the metadata required to retrieve it by fdatasync.
+func (fi *FileIO) DataSync() error {
+	return fdatasync(fi.File)
+}
+
+// SyncRange writes back the dirty pages of the range [off, off+n) by sync_file_range.
+func (fi *FileIO) SyncRange(off, n int64, flags SyncRangeFlag) error {
+	return syncFileRange(fi.File, off, n, flags)
+}
+
+// DataSync flushes the file data and the metadata required to retrieve it by fdatasync.
+// the direct IO bypasses the page cache, but the metadata still needs to be flushed.
+func (dio *DirectIO) DataSync() error {
+	return fdatasync(dio.File)
+}
+
+// SyncRange writes back the dirty pages of the range [off, off+n) by sync_file_range,
+// the direct writes have no dirty pages, it only matters for the buffered writes.
+func (dio *DirectIO) SyncRange(off, n int64, flags SyncRangeFlag) error {
+	return syncFileRange(dio.File, off, n, flags)
+}
@@ -0,0 +1,25 @@
+// +build darwin
+
+package ioengine
+
+import (
+	"errors"
+	"os"
+)
+
+// fdatasync darwin has no fdatasync, it's impled by Sync.
+func fdatasync(fd *os.File) error {
+	return fd.Sync()
+}
+
+// syncFileRange darwin has no sync_file_range, the range can't be written back alone,
+// it falls back to fdatasync if it's required to wait for the write-out.
+func syncFileRange(fd *os.File, off, n int64, flags SyncRangeFlag) error {
+	if off < 0 || n < 0 {
+		return errors.New("sync: invalid range")
+	}
+	if flags&SyncRangeWaitAfter == 0 {
+		return nil
+	}
+	return fdatasync(fd)
+}
@@ -0,0 +1,23 @@
+// +build linux
+
+package ioengine
+
+import (
+	"errors"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// fdatasync flushes the file data and the metadata required to retrieve it, eg: file size.
+func fdatasync(fd *os.File) error {
+	return os.NewSyscallError("FDATASYNC", unix.Fdatasync(int(fd.Fd())))
+}
+
+// syncFileRange the flags are passed through, they match the sync_file_range flags.
+func syncFileRange(fd *os.File, off, n int64, flags SyncRangeFlag) error {
+	if off < 0 || n < 0 {
+		return errors.New("sync: invalid range")
+	}
+	return os.NewSyscallError("SYNC_FILE_RANGE", unix.SyncFileRange(int(fd.Fd()), off, n, int(flags)))
+}
@@ -0,0 +1,102 @@
+// +build linux
+
+package ioengine
+
+import (
+	"testing"
+)
+
+func testSyncRange(t *testing.T, fd File) {
+	b, err := MemAlign(4 * BlockSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	copy(b, []byte("hello world"))
+	if _, err := fd.WriteAt(b, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := fd.SyncRange(0, BlockSize, SyncRangeWrite); err != nil {
+		t.Fatal(err)
+	}
+	if err := fd.SyncRange(BlockSize, 0, SyncRangeWaitBefore|SyncRangeWrite|SyncRangeWaitAfter); err != nil {
+		t.Fatal(err)
+	}
+	if err := fd.SyncRange(-1, BlockSize, SyncRangeWrite); err == nil {
+		t.Fatal("the negative offset should be refused")
+	}
+	if err := fd.DataSync(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFileIOSyncRange(t *testing.T) {
+	fd, err := NewFileIO()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fd.Close()
+	testSyncRange(t, fd)
+}
+
+func TestDirectIOSyncRange(t *testing.T) {
+	fd, err := NewDirectIO()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fd.Close()
+	testSyncRange(t, fd)
+}
+
+func TestMmapSyncRangeFlags(t *testing.T) {
+	fd, err := NewWritableMemoryMap()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fd.Close()
+
+	if _, err := fd.WriteAt([]byte("hello"), 0); err != nil {
+		t.Fatal(err)
+	}
+	// the pages are only scheduled without waiting, they are still dirty
+	if err := fd.SyncRange(0, 0, SyncRangeWrite); err != nil {
+		t.Fatal(err)
+	}
+	if spans := fd.dirty.list(); len(spans) != 1 {
+		t.Fatalf("write: unmatched dirty spans %v", spans)
+	}
+	testSyncRange(t, fd)
+	if spans := fd.dirty.list(); len(spans) != 0 {
+		t.Fatalf("sync: unmatched dirty spans %v", spans)
+	}
+}
+
+func TestAIOSyncRange(t *testing.T) {
+	fd, err := NewAsyncIO()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fd.Close()
+	testSyncRange(t, fd)
+
+	b, err := MemAlign(BlockSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := fd.submitIO(IOCmdPwrite, [][]byte{b}, 8*BlockSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fd.overlapped(0, 8*BlockSize)) != 0 {
+		t.Fatal("the write after the range shouldn't overlap")
+	}
+	if err := fd.SyncRange(8*BlockSize, BlockSize, SyncRangeWrite|SyncRangeWaitAfter); err != nil {
+		t.Fatal(err)
+	}
+	if len(fd.overlapped(8*BlockSize, 9*BlockSize)) != 0 {
+		t.Fatal("the overlapped write should be done")
+	}
+	if _, err := fd.WaitFor(id); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -11,6 +11,13 @@ type DirectIO struct {
 	path string
 	opt  Options
 	once sync.Once
+
+	// buffered the buffered fd of the file for SendTo, it's opened on demand
+	buffered *os.File
+
+	// bufferedMu guards buffered
+	bufferedMu sync.Mutex
+
 	*os.File
 	*FileLock
 }
@@ -21,6 +28,12 @@ func newDirectIO(name string, opt Options) (*DirectIO, error) {
 		return nil, err
 	}
 
+	return newDirectIOFile(name, fd, opt)
+}
+
+// newDirectIOFile wraps the file opened by the direct IO flags, the file is closed if it fails.
+func newDirectIOFile(name string, fd *os.File, opt Options) (*DirectIO, error) {
+	var err error
 	dio := &DirectIO{path: name, opt: opt, File: fd}
 
 	switch opt.FileLock {
@@ -71,6 +84,13 @@ func (dio *DirectIO) FUnlock() error {
 
 // Close impl standard File Close method
 func (dio *DirectIO) Close() error {
+	dio.bufferedMu.Lock()
+	if dio.buffered != nil {
+		dio.buffered.Close()
+		dio.buffered = nil
+	}
+	dio.bufferedMu.Unlock()
+
 	if dio.FileLock == nil {
 		return dio.File.Close()
 	}
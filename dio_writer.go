@@ -0,0 +1,178 @@
+package ioengine
+
+import (
+	"errors"
+	"io"
+)
+
+// defaultDirectWriterSize default DirectWriter buffer size
+const defaultDirectWriterSize = 64 * BlockSize
+
+// ErrWriterClosed write to a closed DirectWriter
+var ErrWriterClosed = errors.New("dio writer: closed")
+
+// DirectWriter impl io.Writer on top of a File which requires aligned IO, eg: DIO or AIO.
+// the arbitrary-sized writes are accumulated into an aligned buffer,
+// only whole blocks are written to the file, the last partial block is
+// padded with zero and the file is truncated back to the logical size.
+// DirectWriter is not safe for concurrent use.
+type DirectWriter struct {
+	fd File
+
+	// buf aligned buffer, its length is a multiple of BlockSize
+	buf []byte
+
+	// n the number of bytes buffered
+	n int
+
+	// off the file offset of buf[0], it's always BlockSize aligned
+	off int64
+
+	err error
+}
+
+// NewDirectWriter returns a DirectWriter appending to the end of fd.
+// size is the buffer size, it will be rounded up to a multiple of BlockSize.
+// if the file size is not block aligned, the tail block is read back into
+// the buffer so that the following writes can be resumed.
+func NewDirectWriter(fd File, size int) (*DirectWriter, error) {
+	if size <= 0 {
+		size = defaultDirectWriterSize
+	}
+	size = (size + BlockSize - 1) &^ (BlockSize - 1)
+
+	buf, err := MemAlign(uint(size))
+	if err != nil {
+		return nil, err
+	}
+
+	stat, err := fd.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	w := &DirectWriter{
+		fd:  fd,
+		buf: buf,
+		off: stat.Size() &^ (BlockSize - 1),
+	}
+
+	// resume the unaligned tail block
+	tail := int(stat.Size() - w.off)
+	if tail > 0 {
+		nr, err := fd.ReadAt(buf[:BlockSize], w.off)
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		if nr < tail {
+			return nil, io.ErrUnexpectedEOF
+		}
+		w.n = tail
+	}
+
+	return w, nil
+}
+
+// Size returns the logical size of the file, including buffered data.
+func (w *DirectWriter) Size() int64 {
+	return w.off + int64(w.n)
+}
+
+// Buffered returns the number of bytes that have been written into the current buffer.
+func (w *DirectWriter) Buffered() int {
+	return w.n
+}
+
+// Write writes len(b) bytes to the buffer, the full buffer is written to the file.
+func (w *DirectWriter) Write(b []byte) (n int, err error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+
+	for len(b) > 0 {
+		nc := copy(w.buf[w.n:], b)
+		w.n += nc
+		n += nc
+		b = b[nc:]
+
+		if w.n == len(w.buf) {
+			if err := w.flushBlocks(); err != nil {
+				return n, err
+			}
+		}
+	}
+
+	return n, nil
+}
+
+// Flush writes all buffered data to the file.
+// the partial tail block is padded with zero before writing,
+// then the file is truncated to the logical size.
+func (w *DirectWriter) Flush() error {
+	if w.err != nil {
+		return w.err
+	}
+	if err := w.flushBlocks(); err != nil {
+		return err
+	}
+	if w.n == 0 {
+		return nil
+	}
+
+	// pad the partial tail block with zero and keep it in the buffer,
+	// it will be rewritten by the next flush.
+	end := (w.n + BlockSize - 1) &^ (BlockSize - 1)
+	for i := w.n; i < end; i++ {
+		w.buf[i] = 0
+	}
+	nw, err := w.fd.WriteAtv([][]byte{w.buf[:end]}, w.off)
+	if err == nil && nw < end {
+		err = io.ErrShortWrite
+	}
+	if err != nil {
+		w.err = err
+		return err
+	}
+	if err := w.fd.Truncate(w.Size()); err != nil {
+		w.err = err
+		return err
+	}
+
+	return nil
+}
+
+// Close flushes the buffered data, the underlying File isn't closed.
+func (w *DirectWriter) Close() error {
+	if w.err == ErrWriterClosed {
+		return nil
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	w.buf = nil
+	w.err = ErrWriterClosed
+	return nil
+}
+
+// flushBlocks writes the full blocks of buffer to the file,
+// the remaining partial block is moved to the front of buffer.
+func (w *DirectWriter) flushBlocks() error {
+	full := w.n &^ (BlockSize - 1)
+	if full == 0 {
+		return nil
+	}
+
+	nw, err := w.fd.WriteAtv([][]byte{w.buf[:full]}, w.off)
+	if err == nil && nw != full {
+		err = io.ErrShortWrite
+	}
+	if err != nil {
+		w.err = err
+		return err
+	}
+
+	w.off += int64(full)
+	w.n = copy(w.buf, w.buf[full:w.n])
+	return nil
+}
@@ -0,0 +1,110 @@
+package ioengine
+
+import (
+	"bytes"
+	"io"
+	"testing"
+)
+
+func TestDirectWriter(t *testing.T) {
+	fd, err := NewDirectIO()
+	if err != nil {
+		t.Fatalf("Failed to new directio: %v", err)
+	}
+	defer fd.Close()
+
+	w, err := NewDirectWriter(fd, BlockSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var expect []byte
+	record := bytes.Repeat([]byte("0123456789"), 100)
+	for i := 0; i < 10; i++ {
+		nw, err := w.Write(record)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if nw != len(record) {
+			t.Fatal("write: short write")
+		}
+		expect = append(expect, record...)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write(record); err != ErrWriterClosed {
+		t.Fatal("write after close should fail")
+	}
+
+	stat, err := fd.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stat.Size() != int64(len(expect)) {
+		t.Fatalf("invalid file size: %d != %d", stat.Size(), len(expect))
+	}
+
+	// resume appending onto a file whose size is not block aligned
+	w, err = NewDirectWriter(fd, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w.Size() != int64(len(expect)) {
+		t.Fatal("resume: invalid logical size")
+	}
+	if _, err := w.Write([]byte("tail")); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	expect = append(expect, []byte("tail")...)
+
+	b, err := MemAlign(uint(len(expect)+BlockSize-1) &^ (BlockSize - 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	nr, err := fd.ReadAt(b, 0)
+	if err != nil && err != io.EOF {
+		t.Fatal(err)
+	}
+	if nr != len(expect) || !bytes.Equal(b[:nr], expect) {
+		t.Fatal("unmatched content")
+	}
+}
+
+// shortFile writes one block less than asked without an error.
+type shortFile struct {
+	File
+}
+
+func (f *shortFile) WriteAtv(bs [][]byte, off int64) (int, error) {
+	n, err := f.File.WriteAtv(bs, off)
+	if n >= BlockSize {
+		n -= BlockSize
+	}
+	return n, err
+}
+
+func TestDirectWriterShortWrite(t *testing.T) {
+	fd, err := NewDirectIO()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fd.Close()
+
+	w, err := NewDirectWriter(&shortFile{File: fd}, BlockSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte("tail")); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Flush(); err != io.ErrShortWrite {
+		t.Fatalf("flush: unexpected error %v", err)
+	}
+	if w.Size() != 4 {
+		t.Fatalf("flush: unmatched size %d", w.Size())
+	}
+}
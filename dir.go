@@ -0,0 +1,125 @@
+package ioengine
+
+import (
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ErrEscapeDir the name is resolved outside of the directory confined by RESOLVE_BENEATH
+var ErrEscapeDir = errors.New("dir: name escapes the directory")
+
+// Dir a directory handle, the names are resolved relative to the opened directory
+// by the *at syscalls, so the operations aren't affected if the path of the directory
+// is renamed or replaced meanwhile. Sync commits the directory entries, it's required
+// for the durability of the created, renamed and removed files.
+// if the directory is confined, the names of Open and the parents of the names of Remove,
+// Rename and Link are resolved beneath it by openat2 RESOLVE_BENEATH,
+// the absolute names and the names escaping by ".." or symbolic links are refused.
+type Dir struct {
+	path string
+	fd   *os.File
+
+	// beneath whether the names are confined beneath the directory
+	beneath bool
+
+	// mu guards the directory offset used by ReadDir
+	mu sync.Mutex
+}
+
+// OpenDir opens the directory, if beneath is true, the names are confined beneath it,
+// Open, Remove, Rename and Link return ErrNotSupported if the confinement isn't supported, eg: linux before 5.6.
+func OpenDir(path string, beneath bool) (*Dir, error) {
+	fd, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+
+	stat, err := fd.Stat()
+	if err != nil {
+		fd.Close()
+		return nil, err
+	}
+	if !stat.IsDir() {
+		fd.Close()
+		return nil, &os.PathError{Op: "opendir", Path: path, Err: errors.New("not a directory")}
+	}
+
+	return &Dir{path: path, fd: fd, beneath: beneath}, nil
+}
+
+// Path returns the path of the directory when it's opened.
+func (d *Dir) Path() string {
+	return d.path
+}
+
+// Fd returns the fd of the directory.
+func (d *Dir) Fd() uintptr {
+	return d.fd.Fd()
+}
+
+// Open opens the name relative to the directory through the IO engine of opt,
+// it returns the same File as Open, MemFD mode isn't supported.
+func (d *Dir) Open(name string, opt Options) (File, error) {
+	if opt.IOEngine == MemFD {
+		return nil, ErrNotSupported
+	}
+	if err := d.check("open", name); err != nil {
+		return nil, err
+	}
+
+	fd, err := d.openat(name, opt)
+	if err != nil {
+		return nil, err
+	}
+	f, err := newFile(d.join(name), fd, opt)
+	if err != nil {
+		return nil, err
+	}
+	return withSyncPolicy(f, opt), nil
+}
+
+// ReadDir reads the directory and returns the entries sorted by name.
+func (d *Dir) ReadDir() ([]os.FileInfo, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if _, err := d.fd.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	list, err := d.fd.Readdir(-1)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return list[i].Name() < list[j].Name()
+	})
+	return list, nil
+}
+
+// Close closes the directory, the files opened by it aren't affected.
+func (d *Dir) Close() error {
+	return d.fd.Close()
+}
+
+// join returns the path of name, it's only used for the errors and the file names.
+func (d *Dir) join(name string) string {
+	return filepath.Join(d.path, name)
+}
+
+// check refuses the names escaping the confined directory lexically,
+// the symbolic links are resolved beneath the directory by openat2.
+func (d *Dir) check(op, name string) error {
+	if !d.beneath {
+		return nil
+	}
+	clean := filepath.Clean(name)
+	if filepath.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return &os.PathError{Op: op, Path: name, Err: ErrEscapeDir}
+	}
+	return nil
+}
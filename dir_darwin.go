@@ -0,0 +1,40 @@
+// +build darwin
+
+package ioengine
+
+import (
+	"os"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// openat darwin has no openat2, the confined directory isn't supported.
+func (d *Dir) openat(name string, opt Options) (*os.File, error) {
+	if d.beneath {
+		return nil, ErrNotSupported
+	}
+
+	fd, err := unix.Openat(int(d.fd.Fd()), name, opt.Flag|syscall.O_CLOEXEC, uint32(opt.Perm.Perm()))
+	if err != nil {
+		return nil, &os.PathError{Op: "openat", Path: d.join(name), Err: err}
+	}
+	f := os.NewFile(uintptr(fd), d.join(name))
+
+	// set no cache like OpenFileWithDIO
+	if opt.IOEngine == DIO {
+		if _, err := unix.FcntlInt(f.Fd(), syscall.F_NOCACHE, 1); err != nil {
+			f.Close()
+			return nil, os.NewSyscallError("Fcntl:NoCache", err)
+		}
+	}
+	return f, nil
+}
+
+// parent darwin has no openat2, the confined directory isn't supported.
+func (d *Dir) parent(op, name string) (int, string, error) {
+	if d.beneath {
+		return -1, "", ErrNotSupported
+	}
+	return int(d.fd.Fd()), name, nil
+}
@@ -0,0 +1,98 @@
+// +build linux
+
+package ioengine
+
+import (
+	"os"
+	"path/filepath"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// resolveBeneath RESOLVE_BENEATH of openat2, the name must be resolved beneath the dirfd
+	resolveBeneath = 0x08
+)
+
+// openHow the struct open_how of openat2
+type openHow struct {
+	flags   uint64
+	mode    uint64
+	resolve uint64
+}
+
+// openat opens the name relative to the directory with the flags required by the engine.
+func (d *Dir) openat(name string, opt Options) (*os.File, error) {
+	flag := opt.Flag | syscall.O_CLOEXEC
+	if opt.IOEngine == DIO || opt.IOEngine == AIO {
+		flag |= syscall.O_DIRECT
+	}
+
+	var fd int
+	var err error
+	if d.beneath {
+		fd, err = openat2(int(d.fd.Fd()), name, flag, uint32(opt.Perm.Perm()))
+		if err == ErrNotSupported {
+			return nil, err
+		}
+	} else {
+		fd, err = unix.Openat(int(d.fd.Fd()), name, flag, uint32(opt.Perm.Perm()))
+	}
+	if err != nil {
+		return nil, &os.PathError{Op: "openat", Path: d.join(name), Err: err}
+	}
+	return os.NewFile(uintptr(fd), d.join(name)), nil
+}
+
+// parent returns the dirfd and the last element of name for the *at syscalls,
+// if the directory is confined, the parent of name is resolved beneath it by openat2,
+// so the symbolic links of the intermediate elements can't escape the directory.
+// the last element isn't followed by unlinkat, renameat and linkat.
+// the dirfd must be closed by closeParent.
+func (d *Dir) parent(op, name string) (int, string, error) {
+	if !d.beneath {
+		return int(d.fd.Fd()), name, nil
+	}
+
+	clean := filepath.Clean(name)
+	dir, base := filepath.Split(clean)
+	if dir == "" {
+		return int(d.fd.Fd()), base, nil
+	}
+	fd, err := openat2(int(d.fd.Fd()), dir, unix.O_PATH|unix.O_DIRECTORY|unix.O_CLOEXEC, 0)
+	if err == ErrNotSupported {
+		return -1, "", err
+	}
+	if err != nil {
+		return -1, "", &os.PathError{Op: op, Path: d.join(name), Err: err}
+	}
+	return fd, base, nil
+}
+
+// openat2 the mode must be 0 unless the file may be created,
+// it returns ErrNotSupported if openat2 isn't supported, and ErrEscapeDir
+// if the name is resolved outside of the directory.
+func openat2(dirfd int, name string, flag int, mode uint32) (int, error) {
+	p, err := unix.BytePtrFromString(name)
+	if err != nil {
+		return -1, err
+	}
+
+	how := openHow{flags: uint64(flag), resolve: resolveBeneath}
+	if flag&(os.O_CREATE|unix.O_TMPFILE) != 0 {
+		how.mode = uint64(mode)
+	}
+	fd, _, errno := unix.Syscall6(sysOpenat2, uintptr(dirfd), uintptr(unsafe.Pointer(p)),
+		uintptr(unsafe.Pointer(&how)), unsafe.Sizeof(how), 0, 0)
+	switch errno {
+	case 0:
+		return int(fd), nil
+	case syscall.ENOSYS:
+		return -1, ErrNotSupported
+	case syscall.EXDEV:
+		return -1, ErrEscapeDir
+	}
+	return -1, errno
+}
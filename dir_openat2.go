@@ -0,0 +1,6 @@
+// +build linux,!mips,!mipsle,!mips64,!mips64le
+
+package ioengine
+
+// sysOpenat2 the openat2 syscall number of the architectures with the unified numbers.
+const sysOpenat2 = 437
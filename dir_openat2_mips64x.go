@@ -0,0 +1,6 @@
+// +build linux,mips64 linux,mips64le
+
+package ioengine
+
+// sysOpenat2 the openat2 syscall number of the mips n64 ABI, it's offset by 5000.
+const sysOpenat2 = 5437
@@ -0,0 +1,6 @@
+// +build linux,mips linux,mipsle
+
+package ioengine
+
+// sysOpenat2 the openat2 syscall number of the mips o32 ABI, it's offset by 4000.
+const sysOpenat2 = 4437
@@ -0,0 +1,182 @@
+// +build linux
+
+package ioengine
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDir(t *testing.T) {
+	path, err := ioutil.TempDir("/tmp/standardio", "dir")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(path)
+
+	d, err := OpenDir(path, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+
+	mmapOpt := DefaultOptions
+	mmapOpt.IOEngine = MMap
+	mmapOpt.MmapSize = BlockSize
+	mmapOpt.MmapWritable = true
+	dioOpt := DefaultOptions
+	dioOpt.IOEngine = DIO
+	aioOpt := DefaultOptions
+	aioOpt.IOEngine = AIO
+
+	b, err := MemAlign(BlockSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	copy(b, []byte("hello world"))
+	for i, opt := range []Options{DefaultOptions, mmapOpt, dioOpt, aioOpt} {
+		fd, err := d.Open(string('a'+rune(i)), opt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := fd.WriteAt(b, 0); err != nil {
+			t.Fatal(err)
+		}
+		if err := fd.Close(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the directory is still usable after its path is renamed
+	moved := path + "-moved"
+	if err := os.Rename(path, moved); err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(moved)
+
+	if err := d.Rename("a", "e"); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Link("b", "f"); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Remove("c"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(moved, "g"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Remove("g"); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Remove("c"); !os.IsNotExist(err) {
+		t.Fatalf("unexpected error %v", err)
+	}
+	if err := d.Sync(); err != nil {
+		t.Fatal(err)
+	}
+
+	list, err := d.ReadDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names string
+	for _, fi := range list {
+		names += fi.Name()
+	}
+	if names != "bdef" {
+		t.Fatalf("unmatched entries %s", names)
+	}
+	data, err := ioutil.ReadFile(filepath.Join(moved, "f"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data[:11]) != "hello world" {
+		t.Fatal("unmatched linked data")
+	}
+}
+
+func TestDirBeneath(t *testing.T) {
+	path, err := ioutil.TempDir("/tmp/standardio", "dir")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(path)
+
+	if err := os.Symlink("/etc/hostname", filepath.Join(path, "escape")); err != nil {
+		t.Fatal(err)
+	}
+
+	d, err := OpenDir(path, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer d.Close()
+
+	fd, err := d.Open("inside", DefaultOptions)
+	if err == ErrNotSupported {
+		t.Skip("openat2 isn't supported")
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	fd.Close()
+
+	for _, name := range []string{"../inside", "/etc/hostname"} {
+		if _, err := d.Open(name, DefaultOptions); err == nil {
+			t.Fatalf("%s should be refused", name)
+		}
+		if err := d.Remove(name); err == nil {
+			t.Fatalf("%s shouldn't be removed", name)
+		}
+	}
+	opt := DefaultOptions
+	opt.Flag = os.O_RDONLY
+	if _, err := d.Open("escape", opt); err == nil {
+		t.Fatal("the symbolic link escaping the directory should be refused")
+	}
+
+	// the intermediate symbolic link escaping the directory
+	outside, err := ioutil.TempDir("/tmp/standardio", "outside")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(outside)
+	if err := ioutil.WriteFile(filepath.Join(outside, "victim"), []byte("hello world"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(path, "link")); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Remove("link/victim"); err == nil {
+		t.Fatal("the file beneath the escaping link shouldn't be removed")
+	}
+	if err := d.Rename("link/victim", "moved"); err == nil {
+		t.Fatal("the file beneath the escaping link shouldn't be renamed")
+	}
+	if err := d.Link("link/victim", "linked"); err == nil {
+		t.Fatal("the file beneath the escaping link shouldn't be linked")
+	}
+	if _, err := os.Stat(filepath.Join(outside, "victim")); err != nil {
+		t.Fatal(err)
+	}
+
+	// the names under the subdirectory are resolved beneath the directory
+	if err := os.Mkdir(filepath.Join(path, "sub"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Rename("inside", "sub/inside"); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Link("sub/inside", "sub/linked"); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Remove("sub/inside"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(path, "sub", "linked")); err != nil {
+		t.Fatal(err)
+	}
+}
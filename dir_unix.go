@@ -0,0 +1,100 @@
+// +build linux darwin
+
+package ioengine
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// Remove removes the file or the empty directory name by unlinkat.
+func (d *Dir) Remove(name string) error {
+	if err := d.check("remove", name); err != nil {
+		return err
+	}
+
+	dirfd, base, err := d.parent("remove", name)
+	if err != nil {
+		return err
+	}
+	defer d.closeParent(dirfd)
+
+	err = unix.Unlinkat(dirfd, base, 0)
+	if err == nil {
+		return nil
+	}
+	err1 := unix.Unlinkat(dirfd, base, unix.AT_REMOVEDIR)
+	if err1 == nil {
+		return nil
+	}
+	// both failed, the error of rmdir is more useful unless the name isn't a directory
+	if err1 != unix.ENOTDIR {
+		err = err1
+	}
+	return &os.PathError{Op: "remove", Path: d.join(name), Err: err}
+}
+
+// Rename renames oldname to newname by renameat, the existing newname is replaced.
+func (d *Dir) Rename(oldname, newname string) error {
+	if err := d.check("rename", oldname); err != nil {
+		return err
+	}
+	if err := d.check("rename", newname); err != nil {
+		return err
+	}
+
+	olddirfd, oldbase, err := d.parent("rename", oldname)
+	if err != nil {
+		return err
+	}
+	defer d.closeParent(olddirfd)
+	newdirfd, newbase, err := d.parent("rename", newname)
+	if err != nil {
+		return err
+	}
+	defer d.closeParent(newdirfd)
+
+	if err := unix.Renameat(olddirfd, oldbase, newdirfd, newbase); err != nil {
+		return &os.LinkError{Op: "rename", Old: d.join(oldname), New: d.join(newname), Err: err}
+	}
+	return nil
+}
+
+// Link creates newname as a hard link to oldname by linkat.
+func (d *Dir) Link(oldname, newname string) error {
+	if err := d.check("link", oldname); err != nil {
+		return err
+	}
+	if err := d.check("link", newname); err != nil {
+		return err
+	}
+
+	olddirfd, oldbase, err := d.parent("link", oldname)
+	if err != nil {
+		return err
+	}
+	defer d.closeParent(olddirfd)
+	newdirfd, newbase, err := d.parent("link", newname)
+	if err != nil {
+		return err
+	}
+	defer d.closeParent(newdirfd)
+
+	if err := unix.Linkat(olddirfd, oldbase, newdirfd, newbase, 0); err != nil {
+		return &os.LinkError{Op: "link", Old: d.join(oldname), New: d.join(newname), Err: err}
+	}
+	return nil
+}
+
+// closeParent closes the dirfd returned by parent unless it's the directory itself.
+func (d *Dir) closeParent(dirfd int) {
+	if dirfd != int(d.fd.Fd()) {
+		unix.Close(dirfd)
+	}
+}
+
+// Sync commits the directory entries to stable storage.
+func (d *Dir) Sync() error {
+	return d.fd.Sync()
+}
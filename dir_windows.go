@@ -0,0 +1,61 @@
+// +build windows
+
+package ioengine
+
+import "os"
+
+// openat windows has no openat, the name is opened by its path,
+// the confined directory isn't supported.
+func (d *Dir) openat(name string, opt Options) (*os.File, error) {
+	if d.beneath {
+		return nil, ErrNotSupported
+	}
+	if opt.IOEngine == DIO {
+		return OpenFileWithDIO(d.join(name), opt.Flag, opt.Perm)
+	}
+	return os.OpenFile(d.join(name), opt.Flag, opt.Perm)
+}
+
+// Remove removes the file or the empty directory name by its path.
+func (d *Dir) Remove(name string) error {
+	if d.beneath {
+		return ErrNotSupported
+	}
+	if err := d.check("remove", name); err != nil {
+		return err
+	}
+	return os.Remove(d.join(name))
+}
+
+// Rename renames oldname to newname by their paths.
+func (d *Dir) Rename(oldname, newname string) error {
+	if d.beneath {
+		return ErrNotSupported
+	}
+	if err := d.check("rename", oldname); err != nil {
+		return err
+	}
+	if err := d.check("rename", newname); err != nil {
+		return err
+	}
+	return os.Rename(d.join(oldname), d.join(newname))
+}
+
+// Link creates newname as a hard link to oldname by their paths.
+func (d *Dir) Link(oldname, newname string) error {
+	if d.beneath {
+		return ErrNotSupported
+	}
+	if err := d.check("link", oldname); err != nil {
+		return err
+	}
+	if err := d.check("link", newname); err != nil {
+		return err
+	}
+	return os.Link(d.join(oldname), d.join(newname))
+}
+
+// Sync windows can't sync the directory, it's skipped.
+func (d *Dir) Sync() error {
+	return nil
+}
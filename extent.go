@@ -0,0 +1,76 @@
+package ioengine
+
+// ExtentFlag the flags of the physical extent, they are the same as linux FIEMAP_EXTENT_*.
+type ExtentFlag uint32
+
+const (
+	// ExtentLast the last extent of the file
+	ExtentLast ExtentFlag = 0x1
+	// ExtentUnknown the physical location isn't known
+	ExtentUnknown ExtentFlag = 0x2
+	// ExtentDelalloc the extent is delayed allocated, the physical location isn't allocated yet
+	ExtentDelalloc ExtentFlag = 0x4
+	// ExtentEncoded the data is compressed or encoded
+	ExtentEncoded ExtentFlag = 0x8
+	// ExtentEncrypted the data is encrypted
+	ExtentEncrypted ExtentFlag = 0x80
+	// ExtentNotAligned the extent isn't aligned to the file system block
+	ExtentNotAligned ExtentFlag = 0x100
+	// ExtentInline the data is stored within the metadata
+	ExtentInline ExtentFlag = 0x200
+	// ExtentTail the data is packed with other files' tails
+	ExtentTail ExtentFlag = 0x400
+	// ExtentUnwritten the extent is allocated but unwritten, eg: by Allocate, it reads as zero
+	ExtentUnwritten ExtentFlag = 0x800
+	// ExtentMerged the extent is merged from the file system blocks
+	ExtentMerged ExtentFlag = 0x1000
+	// ExtentShared the extent is shared with other files, eg: by reflink
+	ExtentShared ExtentFlag = 0x2000
+)
+
+// Extent maps the logical file range [Logical, Logical+Length) to the physical disk offset.
+type Extent struct {
+	Logical  int64
+	Physical int64
+	Length   int64
+	Flags    ExtentFlag
+}
+
+// FragmentationScore returns the fragmentation score of the extents in [0, 1),
+// the logically adjacent extents which are also physically contiguous are one fragment,
+// the score is (fragments - 1) / fragments, 0 means the file is contiguous.
+// the extents without known physical location are ignored.
+func FragmentationScore(extents []Extent) float64 {
+	fragments := 0
+	var prev *Extent
+	for i := range extents {
+		e := &extents[i]
+		if e.Flags&(ExtentUnknown|ExtentDelalloc|ExtentInline) != 0 {
+			continue
+		}
+		if prev == nil || prev.Logical+prev.Length != e.Logical || prev.Physical+prev.Length != e.Physical {
+			fragments++
+		}
+		prev = e
+	}
+	if fragments == 0 {
+		return 0
+	}
+	return float64(fragments-1) / float64(fragments)
+}
+
+// Extents returns the physical extents of the file.
+func (fi *FileIO) Extents() ([]Extent, error) {
+	return fiemap(fi.File)
+}
+
+// Extents returns the physical extents of the file.
+func (dio *DirectIO) Extents() ([]Extent, error) {
+	return fiemap(dio.File)
+}
+
+// Extents returns the physical extents of the file,
+// the dirty pages of the mapping may be reported as delayed allocated.
+func (mmap *MemoryMap) Extents() ([]Extent, error) {
+	return fiemap(mmap.File)
+}
@@ -0,0 +1,10 @@
+// +build darwin
+
+package ioengine
+
+import "os"
+
+// fiemap darwin has no FIEMAP.
+func fiemap(fd *os.File) ([]Extent, error) {
+	return nil, ErrNotSupported
+}
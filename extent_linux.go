@@ -0,0 +1,81 @@
+// +build linux
+
+package ioengine
+
+import (
+	"os"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// fsIOCFiemap _IOWR('f', 11, struct fiemap)
+	fsIOCFiemap = 0xc020660b
+
+	// fiemapBatch the max number of extents fetched by once ioctl
+	fiemapBatch = 256
+)
+
+// fiemapExtent linux struct fiemap_extent
+type fiemapExtent struct {
+	logical    uint64
+	physical   uint64
+	length     uint64
+	reserved64 [2]uint64
+	flags      uint32
+	reserved   [3]uint32
+}
+
+// fiemapHeader linux struct fiemap with the extents array
+type fiemapHeader struct {
+	start         uint64
+	length        uint64
+	flags         uint32
+	mappedExtents uint32
+	extentCount   uint32
+	reserved      uint32
+	extents       [fiemapBatch]fiemapExtent
+}
+
+// fiemap fetches the physical extents of the file by FS_IOC_FIEMAP ioctl,
+// it returns ErrNotSupported if the file system doesn't support it, eg: tmpfs.
+func fiemap(fd *os.File) ([]Extent, error) {
+	var extents []Extent
+	var fm fiemapHeader
+	var start uint64
+
+	for {
+		fm.start = start
+		fm.length = ^uint64(0) - start
+		fm.flags = 0
+		fm.mappedExtents = 0
+		fm.extentCount = fiemapBatch
+
+		_, _, errno := unix.Syscall(unix.SYS_IOCTL, fd.Fd(), fsIOCFiemap, uintptr(unsafe.Pointer(&fm)))
+		if errno == unix.EOPNOTSUPP || errno == unix.ENOTTY {
+			return nil, ErrNotSupported
+		}
+		if errno != 0 {
+			return nil, os.NewSyscallError("FIEMAP", errno)
+		}
+		if fm.mappedExtents == 0 {
+			return extents, nil
+		}
+
+		for _, fe := range fm.extents[:fm.mappedExtents] {
+			extents = append(extents, Extent{
+				Logical:  int64(fe.logical),
+				Physical: int64(fe.physical),
+				Length:   int64(fe.length),
+				Flags:    ExtentFlag(fe.flags),
+			})
+		}
+
+		last := fm.extents[fm.mappedExtents-1]
+		if ExtentFlag(last.flags)&ExtentLast != 0 {
+			return extents, nil
+		}
+		start = last.logical + last.length
+	}
+}
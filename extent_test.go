@@ -0,0 +1,69 @@
+// +build linux
+
+package ioengine
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestFragmentationScore(t *testing.T) {
+	contiguous := []Extent{
+		{Logical: 0, Physical: 8192, Length: 4096},
+		{Logical: 4096, Physical: 12288, Length: 4096},
+	}
+	if score := FragmentationScore(contiguous); score != 0 {
+		t.Fatalf("contiguous: %v", score)
+	}
+
+	fragmented := []Extent{
+		{Logical: 0, Physical: 8192, Length: 4096},
+		{Logical: 4096, Physical: 65536, Length: 4096},
+		{Logical: 8192, Physical: 4096, Length: 4096},
+		{Logical: 12288, Physical: 0, Length: 4096, Flags: ExtentDelalloc},
+	}
+	if score := FragmentationScore(fragmented); score < 0.6 || score > 0.7 {
+		t.Fatalf("fragmented: %v", score)
+	}
+}
+
+func TestExtents(t *testing.T) {
+	fd, err := NewFileIO()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fd.Close()
+
+	if err := fd.Truncate(0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fd.WriteAt(bytes.Repeat([]byte("e"), 3*BlockSize), 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := fd.Sync(); err != nil {
+		t.Fatal(err)
+	}
+
+	extents, err := fd.Extents()
+	if err == ErrNotSupported {
+		t.Skip("fiemap isn't supported")
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(extents) == 0 || extents[len(extents)-1].Flags&ExtentLast == 0 {
+		t.Fatalf("unmatched extents: %v", extents)
+	}
+
+	if err := fd.Allocate(3*BlockSize, 4*BlockSize, false); err != nil {
+		t.Fatal(err)
+	}
+	extents, err = fd.Extents()
+	if err != nil {
+		t.Fatal(err)
+	}
+	last := extents[len(extents)-1]
+	if last.Flags&ExtentUnwritten == 0 || last.Logical+last.Length != 7*BlockSize {
+		t.Fatalf("unmatched preallocated extent: %+v", last)
+	}
+}
@@ -0,0 +1,10 @@
+// +build windows
+
+package ioengine
+
+import "os"
+
+// fiemap isn't supported on windows yet.
+func fiemap(fd *os.File) ([]Extent, error) {
+	return nil, ErrNotSupported
+}
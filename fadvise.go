@@ -0,0 +1,31 @@
+package ioengine
+
+// Fadvise advises the kernel about the access pattern of the file range [off, off+n),
+// n 0 means to the end of file. eg: FadvDontNeed drops the cached pages behind
+// a sequential scan, FadvWillNeed warms the range before a latency-critical read burst.
+// It returns ErrNotSupported if the advice isn't supported by the OS.
+func (fi *FileIO) Fadvise(off, n int64, advice FadviseMode) error {
+	return fadvise(fi.File, off, n, advice)
+}
+
+// Readahead reads the file range [off, off+n) into the page cache,
+// it blocks until the data is read on linux.
+func (fi *FileIO) Readahead(off, n int64) error {
+	return readahead(fi.File, off, n)
+}
+
+// applyHints applies the FileAdvice and FileReadahead options at open time,
+// the hints which aren't supported by the OS are ignored.
+func (fi *FileIO) applyHints() error {
+	if fi.opt.FileAdvice != FadvNormal {
+		if err := fi.Fadvise(0, 0, fi.opt.FileAdvice); err != nil && err != ErrNotSupported {
+			return err
+		}
+	}
+	if fi.opt.FileReadahead > 0 {
+		if err := fi.Readahead(0, fi.opt.FileReadahead); err != nil && err != ErrNotSupported {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+// +build darwin
+
+package ioengine
+
+import (
+	"os"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// fadvise darwin has no posix_fadvise, the readahead is switched by F_RDAHEAD,
+// FadvWillNeed is impled by F_RDADVISE, the others aren't supported.
+func fadvise(fd *os.File, off, n int64, advice FadviseMode) error {
+	switch advice {
+	case FadvNormal, FadvSequential:
+		_, err := unix.FcntlInt(fd.Fd(), unix.F_RDAHEAD, 1)
+		return os.NewSyscallError("FCNTL", err)
+	case FadvRandom:
+		_, err := unix.FcntlInt(fd.Fd(), unix.F_RDAHEAD, 0)
+		return os.NewSyscallError("FCNTL", err)
+	case FadvWillNeed:
+		return readahead(fd, off, n)
+	default:
+		return ErrNotSupported
+	}
+}
+
+// readahead darwin reads ahead by F_RDADVISE, n is limited to int32.
+func readahead(fd *os.File, off, n int64) error {
+	if n <= 0 || n > 1<<31-1 {
+		n = 1<<31 - 1
+	}
+	arg := unix.Radvisory_t{Offset: off, Count: int32(n)}
+	return fcntl(fd, unix.F_RDADVISE, unsafe.Pointer(&arg))
+}
@@ -0,0 +1,27 @@
+// +build linux
+
+package ioengine
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// fadviseFlags translates FadviseMode to the linux posix_fadvise advice.
+var fadviseFlags = map[FadviseMode]int{
+	FadvNormal:     unix.FADV_NORMAL,
+	FadvSequential: unix.FADV_SEQUENTIAL,
+	FadvRandom:     unix.FADV_RANDOM,
+	FadvWillNeed:   unix.FADV_WILLNEED,
+	FadvDontNeed:   unix.FADV_DONTNEED,
+	FadvNoReuse:    unix.FADV_NOREUSE,
+}
+
+func fadvise(fd *os.File, off, n int64, advice FadviseMode) error {
+	flag, ok := fadviseFlags[advice]
+	if !ok {
+		return ErrNotSupported
+	}
+	return os.NewSyscallError("FADVISE", unix.Fadvise(int(fd.Fd()), off, n, flag))
+}
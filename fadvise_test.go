@@ -0,0 +1,35 @@
+package ioengine
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+func TestFadvise(t *testing.T) {
+	fileID++
+	name := fmt.Sprintf("/tmp/standardio/%d", fileID)
+	opt := DefaultOptions
+	opt.FileAdvice = FadvSequential
+	opt.FileReadahead = 1 << 20
+	fd, err := newFileIO(name, opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fd.Close()
+
+	if _, err := fd.WriteAt(bytes.Repeat([]byte("f"), 4*BlockSize), 0); err != nil {
+		t.Fatal(err)
+	}
+	for _, advice := range []FadviseMode{FadvNormal, FadvRandom, FadvWillNeed, FadvDontNeed, FadvNoReuse} {
+		if err := fd.Fadvise(0, 0, advice); err != nil && err != ErrNotSupported {
+			t.Fatalf("fadvise %d: %v", advice, err)
+		}
+	}
+	if err := fd.Fadvise(0, 0, FadviseMode(100)); err != ErrNotSupported {
+		t.Fatal("the unknown advice should be unsupported")
+	}
+	if err := fd.Readahead(0, 2*BlockSize); err != nil && err != ErrNotSupported {
+		t.Fatal(err)
+	}
+}
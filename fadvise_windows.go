@@ -0,0 +1,15 @@
+// +build windows
+
+package ioengine
+
+import "os"
+
+// fadvise isn't supported on windows.
+func fadvise(fd *os.File, off, n int64, advice FadviseMode) error {
+	return ErrNotSupported
+}
+
+// readahead isn't supported on windows.
+func readahead(fd *os.File, off, n int64) error {
+	return ErrNotSupported
+}
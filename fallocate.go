@@ -0,0 +1,116 @@
+package ioengine
+
+import "errors"
+
+// Allocate preallocates the disk space of the file range [off, off+n).
+func (fi *FileIO) Allocate(off, n int64, keepSize bool) error {
+	return allocate(fi.File, off, n, keepSize)
+}
+
+// PunchHole deallocates the disk space of the file range [off, off+n).
+func (fi *FileIO) PunchHole(off, n int64) error {
+	return punchHole(fi.File, off, n)
+}
+
+// ZeroRange zeroes the file range [off, off+n).
+func (fi *FileIO) ZeroRange(off, n int64) error {
+	return zeroRange(fi.File, off, n)
+}
+
+// CollapseRange removes the file range [off, off+n) without leaving a hole.
+func (fi *FileIO) CollapseRange(off, n int64) error {
+	return collapseRange(fi.File, off, n)
+}
+
+// InsertRange inserts a hole of n bytes at off without overwriting data.
+func (fi *FileIO) InsertRange(off, n int64) error {
+	return insertRange(fi.File, off, n)
+}
+
+// Allocate preallocates the disk space of the file range [off, off+n).
+func (dio *DirectIO) Allocate(off, n int64, keepSize bool) error {
+	return allocate(dio.File, off, n, keepSize)
+}
+
+// PunchHole deallocates the disk space of the file range [off, off+n).
+func (dio *DirectIO) PunchHole(off, n int64) error {
+	return punchHole(dio.File, off, n)
+}
+
+// ZeroRange zeroes the file range [off, off+n).
+func (dio *DirectIO) ZeroRange(off, n int64) error {
+	return zeroRange(dio.File, off, n)
+}
+
+// CollapseRange removes the file range [off, off+n) without leaving a hole.
+func (dio *DirectIO) CollapseRange(off, n int64) error {
+	return collapseRange(dio.File, off, n)
+}
+
+// InsertRange inserts a hole of n bytes at off without overwriting data.
+func (dio *DirectIO) InsertRange(off, n int64) error {
+	return insertRange(dio.File, off, n)
+}
+
+// Allocate preallocates the disk space of the file range [off, off+n),
+// the file is remapped if it grows beyond the mapping.
+func (mmap *MemoryMap) Allocate(off, n int64, keepSize bool) error {
+	return mmap.modify(func() error {
+		return allocate(mmap.File, off, n, keepSize)
+	})
+}
+
+// PunchHole deallocates the disk space of the file range [off, off+n).
+func (mmap *MemoryMap) PunchHole(off, n int64) error {
+	return mmap.modify(func() error {
+		return punchHole(mmap.File, off, n)
+	})
+}
+
+// ZeroRange zeroes the file range [off, off+n).
+func (mmap *MemoryMap) ZeroRange(off, n int64) error {
+	return mmap.modify(func() error {
+		return zeroRange(mmap.File, off, n)
+	})
+}
+
+// CollapseRange removes the file range [off, off+n) without leaving a hole.
+func (mmap *MemoryMap) CollapseRange(off, n int64) error {
+	return mmap.modify(func() error {
+		return collapseRange(mmap.File, off, n)
+	})
+}
+
+// InsertRange inserts a hole of n bytes at off without overwriting data.
+func (mmap *MemoryMap) InsertRange(off, n int64) error {
+	return mmap.modify(func() error {
+		return insertRange(mmap.File, off, n)
+	})
+}
+
+// modify runs fn which changes the file underneath the mapping, eg: by fallocate,
+// then the file size is refreshed and the file is remapped if it grows.
+// the private mapping can't change the file.
+func (mmap *MemoryMap) modify(fn func() error) error {
+	if mmap.opt.MmapPrivate {
+		return ErrPrivateMapping
+	}
+
+	mmap.mu.Lock()
+	defer mmap.mu.Unlock()
+
+	if mmap.closed() {
+		return errors.New("mmap: closed")
+	}
+
+	// fn may change the file partially even if it fails
+	ferr := fn()
+	stat, err := mmap.File.Stat()
+	if err != nil {
+		return err
+	}
+	if err := mmap.resize(stat.Size()); err != nil {
+		return err
+	}
+	return ferr
+}
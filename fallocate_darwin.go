@@ -0,0 +1,76 @@
+// +build darwin
+
+package ioengine
+
+import (
+	"os"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// fpunchhole the argument of fcntl F_PUNCHHOLE
+type fpunchhole struct {
+	flags    uint32
+	reserved uint32
+	offset   int64
+	length   int64
+}
+
+// fcntl calls the fcntl syscall with the struct argument.
+func fcntl(fd *os.File, cmd int, arg unsafe.Pointer) error {
+	_, _, errno := unix.Syscall(unix.SYS_FCNTL, fd.Fd(), uintptr(cmd), uintptr(arg))
+	if errno == unix.ENOTSUP || errno == unix.EOPNOTSUPP {
+		return ErrNotSupported
+	}
+	if errno != 0 {
+		return os.NewSyscallError("FCNTL", errno)
+	}
+	return nil
+}
+
+// allocate darwin preallocates the disk space from the physical end of file by F_PREALLOCATE.
+func allocate(fd *os.File, off, n int64, keepSize bool) error {
+	stat, err := fd.Stat()
+	if err != nil {
+		return err
+	}
+	end := off + n
+	if end <= stat.Size() {
+		return nil
+	}
+
+	fstore := unix.Fstore_t{
+		Flags:   unix.F_ALLOCATEALL,
+		Posmode: unix.F_PEOFPOSMODE,
+		Length:  end - stat.Size(),
+	}
+	if err := fcntl(fd, unix.F_PREALLOCATE, unsafe.Pointer(&fstore)); err != nil {
+		return err
+	}
+	if keepSize {
+		return nil
+	}
+	return fd.Truncate(end)
+}
+
+// punchHole darwin deallocates the range by F_PUNCHHOLE, it's supported by APFS.
+func punchHole(fd *os.File, off, n int64) error {
+	arg := fpunchhole{offset: off, length: n}
+	return fcntl(fd, unix.F_PUNCHHOLE, unsafe.Pointer(&arg))
+}
+
+// zeroRange darwin has no fallocate.
+func zeroRange(fd *os.File, off, n int64) error {
+	return ErrNotSupported
+}
+
+// collapseRange darwin has no fallocate.
+func collapseRange(fd *os.File, off, n int64) error {
+	return ErrNotSupported
+}
+
+// insertRange darwin has no fallocate.
+func insertRange(fd *os.File, off, n int64) error {
+	return ErrNotSupported
+}
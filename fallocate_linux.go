@@ -0,0 +1,43 @@
+// +build linux
+
+package ioengine
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// fallocate manipulates the disk space of the file range [off, off+n),
+// it returns ErrNotSupported if the file system doesn't support the mode.
+func fallocate(fd *os.File, mode uint32, off, n int64) error {
+	err := unix.Fallocate(int(fd.Fd()), mode, off, n)
+	if err == unix.EOPNOTSUPP || err == unix.ENOSYS {
+		return ErrNotSupported
+	}
+	return os.NewSyscallError("FALLOCATE", err)
+}
+
+func allocate(fd *os.File, off, n int64, keepSize bool) error {
+	var mode uint32
+	if keepSize {
+		mode = unix.FALLOC_FL_KEEP_SIZE
+	}
+	return fallocate(fd, mode, off, n)
+}
+
+func punchHole(fd *os.File, off, n int64) error {
+	return fallocate(fd, unix.FALLOC_FL_PUNCH_HOLE|unix.FALLOC_FL_KEEP_SIZE, off, n)
+}
+
+func zeroRange(fd *os.File, off, n int64) error {
+	return fallocate(fd, unix.FALLOC_FL_ZERO_RANGE|unix.FALLOC_FL_KEEP_SIZE, off, n)
+}
+
+func collapseRange(fd *os.File, off, n int64) error {
+	return fallocate(fd, unix.FALLOC_FL_COLLAPSE_RANGE, off, n)
+}
+
+func insertRange(fd *os.File, off, n int64) error {
+	return fallocate(fd, unix.FALLOC_FL_INSERT_RANGE, off, n)
+}
@@ -0,0 +1,123 @@
+// +build linux
+
+package ioengine
+
+import (
+	"bytes"
+	"testing"
+)
+
+func testFallocate(t *testing.T, fd File) {
+	if err := fd.Truncate(0); err != nil {
+		t.Fatal(err)
+	}
+
+	// preallocate without changing the file size
+	if err := fd.Allocate(0, 4*BlockSize, true); err == ErrNotSupported {
+		t.Skip("fallocate isn't supported")
+	} else if err != nil {
+		t.Fatal(err)
+	}
+	if stat, err := fd.Stat(); err != nil || stat.Size() != 0 {
+		t.Fatalf("allocate keep size: %v, %v", stat.Size(), err)
+	}
+	if err := fd.Allocate(0, 4*BlockSize, false); err != nil {
+		t.Fatal(err)
+	}
+	if stat, err := fd.Stat(); err != nil || stat.Size() != 4*BlockSize {
+		t.Fatalf("allocate: %v, %v", stat.Size(), err)
+	}
+
+	content, err := MemAlign(4 * BlockSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 4; i++ {
+		copy(content[i*BlockSize:(i+1)*BlockSize], bytes.Repeat([]byte{byte('a' + i)}, BlockSize))
+	}
+	if _, err := fd.WriteAt(content, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := MemAlign(BlockSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fd.PunchHole(BlockSize, BlockSize); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fd.ReadAt(b, BlockSize); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, make([]byte, BlockSize)) {
+		t.Fatal("punch hole: the range isn't zero")
+	}
+	if err := fd.ZeroRange(2*BlockSize, BlockSize); err != nil && err != ErrNotSupported {
+		t.Fatal(err)
+	}
+
+	// reclaim the head of file
+	if err := fd.CollapseRange(0, BlockSize); err == ErrNotSupported {
+		return
+	} else if err != nil {
+		t.Fatal(err)
+	}
+	if stat, err := fd.Stat(); err != nil || stat.Size() != 3*BlockSize {
+		t.Fatalf("collapse: %v, %v", stat.Size(), err)
+	}
+	if _, err := fd.ReadAt(b, 2*BlockSize); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, content[3*BlockSize:]) {
+		t.Fatal("collapse: unmatched content")
+	}
+
+	if err := fd.InsertRange(0, BlockSize); err != nil {
+		t.Fatal(err)
+	}
+	if stat, err := fd.Stat(); err != nil || stat.Size() != 4*BlockSize {
+		t.Fatalf("insert: %v, %v", stat.Size(), err)
+	}
+	if _, err := fd.ReadAt(b, 3*BlockSize); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, content[3*BlockSize:]) {
+		t.Fatal("insert: unmatched content")
+	}
+}
+
+func TestFileIOFallocate(t *testing.T) {
+	fd, err := NewFileIO()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fd.Close()
+	testFallocate(t, fd)
+}
+
+func TestDirectIOFallocate(t *testing.T) {
+	fd, err := NewDirectIO()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fd.Close()
+	testFallocate(t, fd)
+}
+
+func TestMmapFallocate(t *testing.T) {
+	fd, err := NewWritableMemoryMap()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fd.Close()
+	testFallocate(t, fd)
+}
+
+func TestAsyncIOFallocate(t *testing.T) {
+	fd, err := NewAsyncIO()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fd.Close()
+	testFallocate(t, fd)
+}
@@ -0,0 +1,30 @@
+// +build windows
+
+package ioengine
+
+import "os"
+
+// allocate isn't supported on windows yet.
+func allocate(fd *os.File, off, n int64, keepSize bool) error {
+	return ErrNotSupported
+}
+
+// punchHole isn't supported on windows yet.
+func punchHole(fd *os.File, off, n int64) error {
+	return ErrNotSupported
+}
+
+// zeroRange isn't supported on windows yet.
+func zeroRange(fd *os.File, off, n int64) error {
+	return ErrNotSupported
+}
+
+// collapseRange isn't supported on windows.
+func collapseRange(fd *os.File, off, n int64) error {
+	return ErrNotSupported
+}
+
+// insertRange isn't supported on windows.
+func insertRange(fd *os.File, off, n int64) error {
+	return ErrNotSupported
+}
@@ -21,8 +21,19 @@ func newFileIO(name string, opt Options) (*FileIO, error) {
 		return nil, err
 	}
 
+	return newFileIOFile(name, fd, opt)
+}
+
+// newFileIOFile wraps the opened file, the file is closed if it fails.
+func newFileIOFile(name string, fd *os.File, opt Options) (*FileIO, error) {
+	var err error
 	fi := &FileIO{path: name, opt: opt, File: fd}
 
+	if err := fi.applyHints(); err != nil {
+		fd.Close()
+		return nil, err
+	}
+
 	switch opt.FileLock {
 	case None:
 	case ReadWrite:
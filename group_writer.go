@@ -0,0 +1,257 @@
+package ioengine
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// defaultGroupWriterBatchBytes default max bytes written by one group
+const defaultGroupWriterBatchBytes = 1 << 20
+
+// ErrGroupWriterClosed write to a closed GroupWriter
+var ErrGroupWriterClosed = errors.New("group writer: closed")
+
+// groupRequest a pending write waiting for its group to be committed.
+type groupRequest struct {
+	b    []byte
+	n    int
+	err  error
+	done chan struct{}
+}
+
+// GroupWriter appends to the end of file by group commit.
+// the concurrent writes are coalesced into one WriteAtv, the group is made durable
+// by one fdatasync, then all writers of the group are woken with their own result.
+// the first writer of a group is the leader, it waits up to maxDelay or until
+// maxBatchBytes are pending, then commits the group while the next group accumulates.
+// the file should be opened without O_SYNC, otherwise every write is synchronous.
+// GroupWriter is safe for concurrent use, a failed group fails all following writes.
+type GroupWriter struct {
+	fd File
+
+	// maxBatchBytes the max bytes of a group, a larger write is committed alone
+	maxBatchBytes int
+
+	// maxDelay the max time the leader waits for more writers
+	maxDelay time.Duration
+
+	// pending the writes waiting for the next group
+	pending      []*groupRequest
+	pendingBytes int
+
+	// leading whether a leader is waiting for or committing the pending writes
+	leading bool
+
+	// full closed when the pending writes reach maxBatchBytes
+	full chan struct{}
+
+	// off the file offset of the next group
+	off int64
+
+	// syncs the number of committed groups
+	syncs int64
+
+	// leaders the number of running leaders, Close waits for them
+	leaders sync.WaitGroup
+
+	// commit serializes the groups, so that they are written and synced in order
+	commit sync.Mutex
+
+	err    error
+	closed bool
+	mu     sync.Mutex
+}
+
+// NewGroupWriter returns a GroupWriter appending to the end of fd.
+// maxBatchBytes is default 1MB if it's not greater than 0,
+// maxDelay 0 means the leader commits without waiting, the writes arriving
+// during the previous commit are still coalesced.
+func NewGroupWriter(fd File, maxBatchBytes int, maxDelay time.Duration) (*GroupWriter, error) {
+	if maxBatchBytes <= 0 {
+		maxBatchBytes = defaultGroupWriterBatchBytes
+	}
+
+	stat, err := fd.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	return &GroupWriter{
+		fd:            fd,
+		maxBatchBytes: maxBatchBytes,
+		maxDelay:      maxDelay,
+		off:           stat.Size(),
+	}, nil
+}
+
+// Write appends b to the file, it returns after the group containing b is durable.
+// b must not be modified until Write returns.
+func (w *GroupWriter) Write(b []byte) (int, error) {
+	if len(b) == 0 {
+		return 0, nil
+	}
+
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return 0, ErrGroupWriterClosed
+	}
+	if w.err != nil {
+		err := w.err
+		w.mu.Unlock()
+		return 0, err
+	}
+
+	req := &groupRequest{b: b, done: make(chan struct{})}
+	w.pending = append(w.pending, req)
+	w.pendingBytes += len(b)
+	if w.pendingBytes >= w.maxBatchBytes && w.full != nil {
+		close(w.full)
+		w.full = nil
+	}
+
+	leader := !w.leading
+	if leader {
+		w.leading = true
+		w.leaders.Add(1)
+		if w.maxDelay > 0 && w.pendingBytes < w.maxBatchBytes {
+			w.full = make(chan struct{})
+		}
+	}
+	full := w.full
+	w.mu.Unlock()
+
+	if leader {
+		w.lead(full)
+	}
+
+	<-req.done
+	return req.n, req.err
+}
+
+// Size returns the file size after all pending writes are committed.
+func (w *GroupWriter) Size() int64 {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	return w.off + int64(w.pendingBytes)
+}
+
+// lead waits for more writers then commits the pending writes group by group.
+func (w *GroupWriter) lead(full chan struct{}) {
+	defer w.leaders.Done()
+
+	if full != nil {
+		timer := time.NewTimer(w.maxDelay)
+		select {
+		case <-timer.C:
+		case <-full:
+			timer.Stop()
+		}
+	}
+
+	w.commit.Lock()
+	defer w.commit.Unlock()
+
+	for {
+		w.mu.Lock()
+		group, off := w.take()
+		more := len(w.pending) > 0
+		if !more {
+			w.leading = false
+			w.full = nil
+		}
+		err := w.err
+		w.mu.Unlock()
+
+		if err == nil {
+			err = w.flush(group, off)
+		}
+		if err != nil {
+			w.mu.Lock()
+			if w.err == nil {
+				w.err = err
+			}
+			w.mu.Unlock()
+		}
+		for _, req := range group {
+			if err == nil {
+				req.n = len(req.b)
+			}
+			req.err = err
+			close(req.done)
+		}
+
+		if !more {
+			return
+		}
+	}
+}
+
+// take removes the next group from the pending writes up to maxBatchBytes,
+// at least one write is taken. the caller must hold the lock.
+func (w *GroupWriter) take() ([]*groupRequest, int64) {
+	var size, i int
+	for i < len(w.pending) {
+		if i > 0 && size+len(w.pending[i].b) > w.maxBatchBytes {
+			break
+		}
+		size += len(w.pending[i].b)
+		i++
+	}
+
+	group := w.pending[:i:i]
+	w.pending = w.pending[i:]
+	w.pendingBytes -= size
+
+	off := w.off
+	w.off += int64(size)
+	return group, off
+}
+
+// flush writes the group by one WriteAtv and makes it durable by one fdatasync.
+func (w *GroupWriter) flush(group []*groupRequest, off int64) error {
+	bs := make([][]byte, 0, len(group))
+	size := 0
+	for _, req := range group {
+		bs = append(bs, req.b)
+		size += len(req.b)
+	}
+
+	nw, err := w.fd.WriteAtv(bs, off)
+	if err != nil {
+		return err
+	}
+	if nw != size {
+		return errors.New("group writer: short write")
+	}
+
+	w.mu.Lock()
+	w.syncs++
+	w.mu.Unlock()
+
+	return w.fd.DataSync()
+}
+
+// Close commits the pending writes without waiting for maxDelay,
+// it returns the error of the failed group if any, the underlying File isn't closed.
+func (w *GroupWriter) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+	w.closed = true
+	if w.full != nil {
+		close(w.full)
+		w.full = nil
+	}
+	w.mu.Unlock()
+
+	w.leaders.Wait()
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.err
+}
@@ -0,0 +1,71 @@
+package ioengine
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestGroupWriter(t *testing.T) {
+	fileID++
+	name := fmt.Sprintf("/tmp/standardio/%d", fileID)
+	opt := DefaultOptions
+	opt.Flag &^= os.O_SYNC
+	fd, err := newFileIO(name, opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fd.Close()
+	if err := fd.Truncate(0); err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := NewGroupWriter(fd, 4*BlockSize, 5*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	writers := 64
+	record := 100
+	var wg sync.WaitGroup
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			nw, err := w.Write(bytes.Repeat([]byte{byte(i)}, record))
+			if err != nil || nw != record {
+				t.Errorf("write: %d, %v", nw, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := w.Write([]byte("x")); err != ErrGroupWriterClosed {
+		t.Fatal("write after close should fail")
+	}
+	if w.syncs >= int64(writers) {
+		t.Fatalf("the writes aren't grouped: %d syncs", w.syncs)
+	}
+
+	b := make([]byte, writers*record)
+	if _, err := fd.ReadAt(b, 0); err != nil {
+		t.Fatal(err)
+	}
+	seen := make(map[byte]bool)
+	for i := 0; i < writers; i++ {
+		chunk := b[i*record : (i+1)*record]
+		if !bytes.Equal(chunk, bytes.Repeat(chunk[:1], record)) {
+			t.Fatal("the records are interleaved")
+		}
+		seen[chunk[0]] = true
+	}
+	if len(seen) != writers {
+		t.Fatalf("unmatched records: %d", len(seen))
+	}
+}
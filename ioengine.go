@@ -3,6 +3,7 @@ package ioengine
 import (
 	"errors"
 	"os"
+	"time"
 )
 
 // IOMode specifies disk I/O mode, default StandardIO.
@@ -17,6 +18,8 @@ const (
 	DIO
 	// AIO indicates that disk I/O using Async I/O by libaio or io_uring
 	AIO
+	// MemFD indicates that the anonymous in-memory file created by memfd_create, linux only
+	MemFD
 )
 
 // FileLockMode specifies file lock mode, default None.
@@ -41,6 +44,85 @@ const (
 	IOUring
 )
 
+// MadviseMode specifies the access pattern hint of the mapped memory, default MadvNormal.
+type MadviseMode int
+
+const (
+	// MadvNormal indicates that no special treatment
+	MadvNormal MadviseMode = iota
+	// MadvSequential indicates that pages will be accessed in sequential order
+	MadvSequential
+	// MadvRandom indicates that pages will be accessed in random order
+	MadvRandom
+	// MadvWillNeed indicates that pages will be accessed in the near future
+	MadvWillNeed
+	// MadvDontNeed indicates that pages will not be accessed in the near future
+	MadvDontNeed
+	// MadvHugePage indicates that pages are backed by transparent huge pages, linux only
+	MadvHugePage
+	// MadvCold indicates that pages are deactivated without freeing, linux v5.4
+	MadvCold
+	// MadvPageOut indicates that pages are reclaimed
+	MadvPageOut
+)
+
+// FadviseMode specifies the access pattern hint of the file data, default FadvNormal.
+type FadviseMode int
+
+const (
+	// FadvNormal indicates that no special treatment
+	FadvNormal FadviseMode = iota
+	// FadvSequential indicates that data will be accessed in sequential order
+	FadvSequential
+	// FadvRandom indicates that data will be accessed in random order
+	FadvRandom
+	// FadvWillNeed indicates that data will be accessed in the near future
+	FadvWillNeed
+	// FadvDontNeed indicates that data will not be accessed in the near future
+	FadvDontNeed
+	// FadvNoReuse indicates that data will be accessed only once
+	FadvNoReuse
+)
+
+// SyncPolicy the background write-back policy of the file opened by Open,
+// the zero value disables it. the policy makes the writes durable without O_SYNC.
+type SyncPolicy struct {
+	// Bytes makes the file durable by fdatasync once the unsynced bytes reach it, 0 disables it.
+	Bytes int64
+
+	// Interval makes the file durable by fdatasync periodically if there are
+	// unsynced bytes, 0 disables it.
+	Interval time.Duration
+
+	// WriteBackBytes starts the write-out of the dirty pages by sync_file_range without
+	// waiting once the bytes written since the last write-out reach it, it trickles the
+	// pages out so that the next fdatasync doesn't stall, 0 disables it.
+	WriteBackBytes int64
+}
+
+// enabled whether the policy syncs the file in background.
+func (p SyncPolicy) enabled() bool {
+	return p.Bytes > 0 || p.Interval > 0 || p.WriteBackBytes > 0
+}
+
+// SyncRangeFlag specifies how SyncRange writes back the range,
+// the flags match the linux sync_file_range flags and can be combined.
+type SyncRangeFlag int
+
+const (
+	// SyncRangeWaitBefore waits for the write-out of the pages already submitted in the range
+	SyncRangeWaitBefore SyncRangeFlag = 1 << iota
+	// SyncRangeWrite starts the write-out of the dirty pages in the range
+	SyncRangeWrite
+	// SyncRangeWaitAfter waits for the write-out of the range after starting it
+	SyncRangeWaitAfter
+)
+
+var (
+	// ErrNotSupported the operation isn't supported by the OS or file system
+	ErrNotSupported = errors.New("Operation not supported")
+)
+
 // Options are params for creating IOEngine.
 type Options struct {
 	// IOEngine io mode
@@ -55,13 +137,51 @@ type Options struct {
 	// FileLock file lock mode, default none
 	FileLock FileLockMode
 
-	// MmapSize mmap file size in memory
+	// FileAdvice the access pattern hint applied to the whole file at open time
+	// on StandardIO mode, default FadvNormal.
+	FileAdvice FadviseMode
+
+	// FileReadahead the bytes from the beginning of file to be read ahead into
+	// the page cache at open time on StandardIO mode, 0 means no readahead.
+	FileReadahead int64
+
+	// MmapSize mmap file size in memory, it's the initial mapping size,
+	// the mapping grows with the file.
 	MmapSize int
 
 	// MmapWritable whether to allow mmap write
-	// if true, it will be use mmap write instead of standardIO write, not implemented yet.
+	// if true, it will be use mmap write instead of standardIO write,
+	// the file must be opened with O_RDWR.
 	MmapWritable bool
 
+	// MmapPrivate whether to map the file by private copy-on-write mapping,
+	// the writes are copied into the private pages and never written back to the file,
+	// the file can't be grown or synced, it isn't supported on windowed mode.
+	MmapPrivate bool
+
+	// MmapAdvice the access pattern hint applied to the mapping, default MadvNormal.
+	MmapAdvice MadviseMode
+
+	// MmapPopulate whether to prefault the mapping at open time by MAP_POPULATE.
+	MmapPopulate bool
+
+	// MmapLock whether to lock the mapped file in memory by mlock.
+	// it's limited by RLIMIT_MEMLOCK, it's ignored on windowed mode.
+	MmapLock bool
+
+	// MmapWindowSize if it's greater than 0, the file is mapped by fixed-size
+	// aligned windows on demand instead of mapping the whole file,
+	// it's rounded up to a multiple of the page size.
+	MmapWindowSize int
+
+	// MmapMaxWindows the max number of mapped windows on windowed mode,
+	// the least recently used windows are unmapped beyond it, default 64.
+	MmapMaxWindows int
+
+	// SyncPolicy the background write-back policy, Open returns a *SyncedFile
+	// wrapping the engine if it's enabled, default disabled.
+	SyncPolicy SyncPolicy
+
 	// AIO async IO mode, defaul libaio, the io_uring isn't implemented yet.
 	AIO AIOMode
 
@@ -74,17 +194,39 @@ type Options struct {
 
 // DefaultOptions is recommended options, you can modify these to suit your needs.
 var DefaultOptions = Options{
-	IOEngine:      StandardIO,
-	Flag:          os.O_RDWR | os.O_CREATE | os.O_SYNC,
-	Perm:          0644,
-	FileLock:      None,
-	MmapSize:      1<<30 - 1,
-	MmapWritable:  false,
-	AIO:           Libaio,
-	AIOQueueDepth: 1024,
-	AIOTimeout:    0,
+	IOEngine:       StandardIO,
+	Flag:           os.O_RDWR | os.O_CREATE | os.O_SYNC,
+	Perm:           0644,
+	FileLock:       None,
+	FileAdvice:     FadvNormal,
+	FileReadahead:  0,
+	MmapSize:       1<<30 - 1,
+	MmapWritable:   false,
+	MmapPrivate:    false,
+	MmapAdvice:     MadvNormal,
+	MmapPopulate:   false,
+	MmapLock:       false,
+	MmapWindowSize: 0,
+	MmapMaxWindows: 64,
+	AIO:            Libaio,
+	AIOQueueDepth:  1024,
+	AIOTimeout:     0,
 }
 
+// ThroughputOptions likes DefaultOptions, but the file isn't opened with O_SYNC,
+// the writes are made durable by the background SyncPolicy instead,
+// it's recommended for the throughput-oriented files, eg: logs and bulk loads.
+var ThroughputOptions = func() Options {
+	opt := DefaultOptions
+	opt.Flag &^= os.O_SYNC
+	opt.SyncPolicy = SyncPolicy{
+		Bytes:          64 << 20,
+		Interval:       time.Second,
+		WriteBackBytes: 1 << 20,
+	}
+	return opt
+}()
+
 // File a unified common file operation interface
 type File interface {
 	// Fd returns the Unix fd or Windows handle referencing the open file.
@@ -134,7 +276,8 @@ type File interface {
 
 	// Seek sets the offset for the next Read or Write on file to offset, interpreted
 	// according to whence: 0 means relative to the origin of the file, 1 means
-	// relative to the current offset, and 2 means relative to the end.
+	// relative to the current offset, and 2 means relative to the end,
+	// SeekData and SeekHole mean the next data or hole at or after the offset.
 	// It returns the new offset and an error, if any.
 	// The behavior of Seek on a file opened with O_APPEND is not specified.
 	Seek(offset int64, whence int) (int64, error)
@@ -144,6 +287,37 @@ type File interface {
 	// If there is an error, it will be of type *PathError.
 	Truncate(size int64) error
 
+	// Allocate preallocates the disk space of the range [off, off+n),
+	// if keepSize is true, the file size isn't changed even if the range exceeds it.
+	// It returns ErrNotSupported if the file system refuses.
+	Allocate(off, n int64, keepSize bool) error
+
+	// PunchHole deallocates the disk space of the range [off, off+n),
+	// the range reads as zero and the file size isn't changed.
+	PunchHole(off, n int64) error
+
+	// ZeroRange zeroes the range [off, off+n) and keeps its disk space allocated,
+	// the file size isn't changed.
+	ZeroRange(off, n int64) error
+
+	// CollapseRange removes the range [off, off+n), the data after it is shifted
+	// to off and the file size is reduced by n. on linux, off and n must be
+	// a multiple of the file system block size and the range can't reach the end of file.
+	CollapseRange(off, n int64) error
+
+	// InsertRange inserts a hole of n bytes at off, the data after off is shifted
+	// by n and the file size is increased by n. on linux, off and n must be
+	// a multiple of the file system block size and off must be within the file.
+	InsertRange(off, n int64) error
+
+	// Extents returns the logical to physical extent mappings of the file by FIEMAP.
+	// It returns ErrNotSupported if the file system refuses.
+	Extents() ([]Extent, error)
+
+	// Statx returns the extended status of the file, eg: birth time, attributes and
+	// allocated blocks, it's filled by statx on linux and by fstat on darwin.
+	Statx() (*Statx, error)
+
 	// FLock the lock is suggested and exclusive
 	FLock() error
 
@@ -156,6 +330,17 @@ type File interface {
 	// of recently written data to disk.
 	Sync() error
 
+	// DataSync likes Sync, but only flushes the metadata required to retrieve
+	// the data, eg: file size, not the modification time.
+	DataSync() error
+
+	// SyncRange writes back the dirty pages of the range [off, off+n) by flags,
+	// n == 0 means to the end of file. It neither flushes the metadata nor the
+	// disk write cache, so it isn't durable alone, it's used to trickle the dirty
+	// pages out before Sync or DataSync. on non-linux, it falls back to DataSync
+	// if SyncRangeWaitAfter is set, otherwise it does nothing.
+	SyncRange(off, n int64, flags SyncRangeFlag) error
+
 	// Close closes the File, rendering it unusable for I/O.
 	Close() error
 
@@ -164,7 +349,24 @@ type File interface {
 }
 
 // Open opens the named file for reading
+// if opt.SyncPolicy is enabled, the file is wrapped by *SyncedFile.
 func Open(name string, opt Options) (File, error) {
+	fd, err := open(name, opt)
+	if err != nil {
+		return fd, err
+	}
+	return withSyncPolicy(fd, opt), nil
+}
+
+// withSyncPolicy wraps fd by *SyncedFile if opt.SyncPolicy is enabled.
+func withSyncPolicy(fd File, opt Options) File {
+	if !opt.SyncPolicy.enabled() {
+		return fd
+	}
+	return newSyncedFile(fd, opt.SyncPolicy)
+}
+
+func open(name string, opt Options) (File, error) {
 	switch opt.IOEngine {
 	case StandardIO:
 		return newFileIO(name, opt)
@@ -174,7 +376,29 @@ func Open(name string, opt Options) (File, error) {
 		return newDirectIO(name, opt)
 	case AIO:
 		return newAsyncIO(name, opt)
+	case MemFD:
+		return newMemFile(name, opt)
+	default:
+		return nil, errors.New("Unsupported IO Engine")
+	}
+}
+
+// newFile wraps the opened fd by the IO engine, fd must be opened with the flags
+// required by the engine, eg: O_DIRECT on DIO mode, it's closed if it fails.
+func newFile(name string, fd *os.File, opt Options) (File, error) {
+	switch opt.IOEngine {
+	case StandardIO:
+		return newFileIOFile(name, fd, opt)
+	case MMap:
+		return newMemoryMapFile(name, fd, opt)
+	case DIO:
+		return newDirectIOFile(name, fd, opt)
+	case AIO:
+		return newAsyncIOFile(fd, opt)
+	case MemFD:
+		return NewMemFile(fd, opt)
 	default:
+		fd.Close()
 		return nil, errors.New("Unsupported IO Engine")
 	}
 }
@@ -0,0 +1,24 @@
+package ioengine
+
+// SealMode specifies the seals of MemFile, they restrict the operations on the file.
+type SealMode int
+
+const (
+	// SealSeal prevents further seals from being set
+	SealSeal SealMode = 1 << iota
+	// SealShrink prevents the file from shrinking
+	SealShrink
+	// SealGrow prevents the file from growing
+	SealGrow
+	// SealWrite prevents the file content from being modified
+	SealWrite
+)
+
+// MemFile MemFD IO mode
+// the anonymous in-memory file is created by memfd_create, it never touches disk,
+// the reads and writes are impled by the shared writable mapping of MemoryMap.
+// the file can be sealed to be immutable, and the fd can be handed to the child
+// processes, eg: by exec.Cmd ExtraFiles, they can map the same pages by NewMemFile.
+type MemFile struct {
+	*MemoryMap
+}
@@ -0,0 +1,132 @@
+// +build linux
+
+package ioengine
+
+import (
+	"errors"
+	"os"
+	"sync/atomic"
+
+	"golang.org/x/sys/unix"
+)
+
+// newMemFile creates an anonymous in-memory file, the name is only used for debugging,
+// it's shown as the target of the symbolic link in /proc/self/fd.
+func newMemFile(name string, opt Options) (*MemFile, error) {
+	fd, err := unix.MemfdCreate(name, unix.MFD_CLOEXEC|unix.MFD_ALLOW_SEALING)
+	if err != nil {
+		return nil, os.NewSyscallError("MEMFD_CREATE", err)
+	}
+	return NewMemFile(os.NewFile(uintptr(fd), "memfd:"+name), opt)
+}
+
+// NewMemFile maps the memfd, eg: inherited from the parent process.
+// the fd is owned by MemFile, it's closed by Close or if NewMemFile fails.
+// if the file is sealed by SealWrite, it's mapped read-only.
+func NewMemFile(fd *os.File, opt Options) (*MemFile, error) {
+	seals, err := unix.FcntlInt(fd.Fd(), unix.F_GET_SEALS, 0)
+	if err != nil {
+		fd.Close()
+		return nil, os.NewSyscallError("F_GET_SEALS", err)
+	}
+
+	// the whole file is always mapped writable unless it's sealed
+	opt.IOEngine = MemFD
+	opt.MmapWritable = SealMode(seals)&SealWrite == 0
+	opt.MmapPrivate = false
+	opt.MmapWindowSize = 0
+
+	mmap, err := newMemoryMapFile(fd.Name(), fd, opt)
+	if err != nil {
+		return nil, err
+	}
+	return &MemFile{MemoryMap: mmap}, nil
+}
+
+// Seal adds the seals to the file, the seals can't be removed.
+// the shared mapping must be unmapped before SealWrite is added,
+// so that the file is mapped read-only again, it fails if any slice is borrowed.
+// Seal must not be called concurrently with the writes.
+func (mf *MemFile) Seal(seals SealMode) error {
+	mmap := mf.MemoryMap
+
+	mmap.mu.Lock()
+	defer mmap.mu.Unlock()
+
+	if mmap.closed() {
+		return errors.New("memfd: closed")
+	}
+	if seals&SealWrite != 0 && mmap.opt.MmapWritable {
+		return mf.sealWrite(seals)
+	}
+	return mf.addSeals(seals)
+}
+
+// Seals returns the seals of the file.
+func (mf *MemFile) Seals() (SealMode, error) {
+	seals, err := unix.FcntlInt(mf.MemoryMap.File.Fd(), unix.F_GET_SEALS, 0)
+	if err != nil {
+		return 0, os.NewSyscallError("F_GET_SEALS", err)
+	}
+	return SealMode(seals), nil
+}
+
+func (mf *MemFile) addSeals(seals SealMode) error {
+	if _, err := unix.FcntlInt(mf.MemoryMap.File.Fd(), unix.F_ADD_SEALS, int(seals)); err != nil {
+		return os.NewSyscallError("F_ADD_SEALS", err)
+	}
+	return nil
+}
+
+// sealWrite adds the seals with SealWrite, the shared mapping of the file
+// opened with O_RDWR may be writable even if it's mapped read-only,
+// so that the file is unmapped before sealing and mapped read-only after sealing.
+// the caller must hold the write lock.
+func (mf *MemFile) sealWrite(seals SealMode) error {
+	mmap := mf.MemoryMap
+
+	mmap.retiredMu.Lock()
+	borrowed := atomic.LoadInt64(&mmap.refs) > 0 || len(mmap.retired) > 0
+	mmap.retiredMu.Unlock()
+	if borrowed {
+		return errors.New("memfd: can't seal write while slices are borrowed")
+	}
+
+	length := len(mmap.data)
+	if err := Munmap(mmap.data); err != nil {
+		return err
+	}
+	mmap.data = nil
+
+	opt := mmap.opt
+	serr := mf.addSeals(seals)
+	if serr == nil {
+		opt.MmapWritable = false
+	}
+
+	// the file is mapped writable again if it fails to be sealed
+	data, err := mmapFile(mmap.File, 0, length, opt)
+	if err != nil {
+		return err
+	}
+	mmap.data = data
+	mmap.opt = opt
+	if err := mmap.advise(); err != nil {
+		return err
+	}
+	if err := mmap.lock(0); err != nil {
+		return err
+	}
+
+	return serr
+}
+
+// FLock the lock is on the memfd itself, there is no path to hold the lock file.
+func (mf *MemFile) FLock() error {
+	return unix.Flock(int(mf.MemoryMap.File.Fd()), unix.LOCK_EX)
+}
+
+// FUnlock unlock the memfd.
+func (mf *MemFile) FUnlock() error {
+	return unix.Flock(int(mf.MemoryMap.File.Fd()), unix.LOCK_UN)
+}
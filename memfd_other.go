@@ -0,0 +1,25 @@
+// +build !linux
+
+package ioengine
+
+import "os"
+
+// newMemFile memfd_create is linux only.
+func newMemFile(name string, opt Options) (*MemFile, error) {
+	return nil, ErrNotSupported
+}
+
+// NewMemFile memfd is linux only.
+func NewMemFile(fd *os.File, opt Options) (*MemFile, error) {
+	return nil, ErrNotSupported
+}
+
+// Seal memfd is linux only.
+func (mf *MemFile) Seal(seals SealMode) error {
+	return ErrNotSupported
+}
+
+// Seals memfd is linux only.
+func (mf *MemFile) Seals() (SealMode, error) {
+	return 0, ErrNotSupported
+}
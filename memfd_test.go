@@ -0,0 +1,70 @@
+// +build linux
+
+package ioengine
+
+import (
+	"bytes"
+	"os/exec"
+	"testing"
+)
+
+func TestMemFile(t *testing.T) {
+	opt := DefaultOptions
+	opt.IOEngine = MemFD
+	opt.MmapSize = BlockSize
+	file, err := Open("spill", opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	content := bytes.Repeat([]byte("0123456789"), 1000)
+	if _, err := file.WriteAt(content, 0); err != nil {
+		t.Fatal(err)
+	}
+	b := make([]byte, len(content))
+	if _, err := file.ReadAt(b, 0); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, content) {
+		t.Fatal("unmatched content")
+	}
+
+	mf := file.(*MemFile)
+	if err := mf.Seal(SealShrink | SealGrow | SealWrite); err != nil {
+		t.Fatal(err)
+	}
+	seals, err := mf.Seals()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if seals&SealWrite == 0 {
+		t.Fatalf("unmatched seals: %x", seals)
+	}
+	if _, err := file.WriteAt([]byte("x"), 0); err == nil {
+		t.Fatal("write a sealed file should fail")
+	}
+	if err := file.Truncate(0); err == nil {
+		t.Fatal("truncate a sealed file should fail")
+	}
+	if _, err := file.ReadAt(b, 0); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, content) {
+		t.Fatal("unmatched sealed content")
+	}
+
+	// hand the fd to the child process
+	if _, err := exec.LookPath("cat"); err != nil {
+		t.Skip("cat not found")
+	}
+	cmd := exec.Command("cat", "/dev/fd/3")
+	cmd.ExtraFiles = append(cmd.ExtraFiles, mf.MemoryMap.File)
+	out, err := cmd.Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out, content) {
+		t.Fatal("unmatched content in the child process")
+	}
+}
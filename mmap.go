@@ -3,42 +3,144 @@ package ioengine
 import (
 	"errors"
 	"io"
+	"math"
 	"os"
 	"runtime"
 	"sync"
+	"sync/atomic"
+	"unicode/utf8"
+)
+
+// mmapWriteToChunk the max bytes written by once call of MemoryMap WriteTo and SendTo.
+const mmapWriteToChunk = 1 << 20
+
+var (
+	// ErrPrivateMapping the private mapping can't be written back to the file.
+	ErrPrivateMapping = errors.New("mmap: private mapping isn't written back to the file")
+
+	// errPrivateWindowed the private pages would be lost when the window is evicted
+	errPrivateWindowed = errors.New("mmap: private mapping can't be windowed")
 )
 
 // MemoryMap disk IO mode
 // page faults and dirty page writes can degrade mmap performance
-// we impl ReadAt by mmap, other API impled by standardIO.
+// we impl ReadAt and the sequential Read by mmap, other API impled by standardIO.
+// if MmapWritable is set, the writes are copied into the mapping
+// and the file is grown by ftruncate and remapped as needed.
+// the file size is tracked, only the bytes within it are readable,
+// the file is remapped transparently when writes or Truncate grow it,
+// the reads beyond it re-check the file size for the data appended by others.
+// if MmapWindowSize is set, the file is mapped by fixed-size windows on demand.
+// if MmapPrivate is set, the writes are copied into the private copy-on-write pages,
+// the file is never modified by the MemoryMap.
 type MemoryMap struct {
 	path string
 	opt  Options
 	data []byte
+
+	// size the file size, the mapping may be larger than it
+	size int64
+
+	// mu guards data and size, the remap holds the write lock
+	mu sync.RWMutex
+
+	// refs the number of slices borrowed from data, it's updated atomically.
+	refs int64
+
+	// retired the old mappings which are still borrowed by slices,
+	// they will be unmapped when all slices are released.
+	retired []*mapping
+
+	// retiredMu guards retired
+	retiredMu sync.Mutex
+
+	// win maps the file by windows instead of data if MmapWindowSize is set
+	win *mmapWindows
+
+	// dirty the modified pages on writable mode
+	dirty dirtySpans
+
+	// offset the Read, Write and Seek offset, it's independent of the file offset
+	offset int64
+
+	// prevRune the offset of the previous rune read, or -1
+	prevRune int64
+
+	// cursor guards offset and prevRune
+	cursor sync.Mutex
+
 	once sync.Once
 	*os.File
 	*FileLock
 }
 
 func newMemoryMap(name string, opt Options) (*MemoryMap, error) {
+	if opt.MmapPrivate && opt.MmapWindowSize > 0 {
+		return nil, errPrivateWindowed
+	}
+
 	fd, err := os.OpenFile(name, opt.Flag, opt.Perm)
 	if err != nil {
 		return nil, err
 	}
 
-	data, err := Mmap(fd, 0, opt.MmapSize, opt.MmapWritable)
+	return newMemoryMapFile(name, fd, opt)
+}
+
+// newMemoryMapFile maps the opened file, the file is closed if it fails.
+func newMemoryMapFile(name string, fd *os.File, opt Options) (*MemoryMap, error) {
+	if opt.MmapPrivate && opt.MmapWindowSize > 0 {
+		fd.Close()
+		return nil, errPrivateWindowed
+	}
+
+	stat, err := fd.Stat()
 	if err != nil {
+		fd.Close()
 		return nil, err
 	}
-	if err := Madvise(data); err != nil {
+
+	if opt.MmapWindowSize > 0 {
+		mmap := &MemoryMap{
+			path:     name,
+			opt:      opt,
+			size:     stat.Size(),
+			prevRune: -1,
+			win:      newMmapWindows(fd, opt),
+			File:     fd,
+		}
+		return mmap, nil
+	}
+
+	// map the whole file even if it's larger than MmapSize
+	length := opt.MmapSize
+	if stat.Size() > int64(length) {
+		length = int(stat.Size())
+	}
+	data, err := mmapFile(fd, 0, length, opt)
+	if err != nil {
+		fd.Close()
 		return nil, err
 	}
 
 	mmap := &MemoryMap{
-		path: name,
-		opt:  opt,
-		data: data,
-		File: fd,
+		path:     name,
+		opt:      opt,
+		data:     data,
+		size:     stat.Size(),
+		prevRune: -1,
+		File:     fd,
+	}
+
+	if err := mmap.advise(); err != nil {
+		Munmap(data)
+		fd.Close()
+		return nil, err
+	}
+	if err := mmap.lock(0); err != nil {
+		Munmap(data)
+		fd.Close()
+		return nil, err
 	}
 
 	// runtime.SetFinalizer(mmap, (*mmap).Close())
@@ -51,16 +153,46 @@ func (mmap *MemoryMap) ReadAt(b []byte, off int64) (int, error) {
 	if len(b) == 0 {
 		return 0, nil
 	}
-	if mmap.data == nil {
-		return 0, errors.New("mmap: closed")
-	}
 	if off < 0 {
 		return 0, errors.New("negative offset")
 	}
-	if int64(len(mmap.data)) < off {
+
+	// the file may be extended by another handle or process
+	if off+int64(len(b)) > mmap.fileSize() {
+		if _, err := mmap.refresh(); err != nil {
+			return 0, err
+		}
+	}
+
+	mmap.mu.RLock()
+	defer mmap.mu.RUnlock()
+
+	if mmap.closed() {
+		return 0, errors.New("mmap: closed")
+	}
+	if off >= mmap.size {
 		return 0, io.EOF
 	}
-	n := copy(b, mmap.data[off:])
+
+	rb := b
+	if int64(len(rb)) > mmap.size-off {
+		rb = rb[:mmap.size-off]
+	}
+
+	var n int
+	var err error
+	if gerr := mmap.guard(off+int64(len(rb)), func() {
+		if mmap.win != nil {
+			n, err = mmap.win.copyAt(rb, off, false)
+		} else {
+			n = copy(rb, mmap.data[off:])
+		}
+	}); gerr != nil {
+		return 0, gerr
+	}
+	if err != nil {
+		return n, err
+	}
 	if n < len(b) {
 		return n, io.EOF
 	}
@@ -68,6 +200,591 @@ func (mmap *MemoryMap) ReadAt(b []byte, off int64) (int, error) {
 	return n, nil
 }
 
+// WriteAt writes len(b) bytes to the File starting at byte offset off.
+// on writable mode, the data is copied into the mapping,
+// the file will be grown and remapped if the write exceeds it.
+// on private mode, the data is copied into the private pages within the file size.
+func (mmap *MemoryMap) WriteAt(b []byte, off int64) (int, error) {
+	if mmap.opt.MmapPrivate {
+		return mmap.writePrivate(b, off)
+	}
+	if !mmap.opt.MmapWritable {
+		n, err := mmap.File.WriteAt(b, off)
+		if n > 0 {
+			if rerr := mmap.extend(off + int64(n)); err == nil {
+				err = rerr
+			}
+		}
+		return n, err
+	}
+	if len(b) == 0 {
+		return 0, nil
+	}
+	if off < 0 {
+		return 0, errors.New("negative offset")
+	}
+
+	end := off + int64(len(b))
+	for {
+		if err := mmap.grow(end); err != nil {
+			return 0, err
+		}
+
+		mmap.mu.RLock()
+		if mmap.closed() {
+			mmap.mu.RUnlock()
+			return 0, errors.New("mmap: closed")
+		}
+		// the file may be truncated concurrently before we copy
+		if end <= mmap.size {
+			var n int
+			var err error
+			if gerr := mmap.guard(end, func() {
+				if mmap.win != nil {
+					n, err = mmap.win.copyAt(b, off, true)
+				} else {
+					n = copy(mmap.data[off:end], b)
+				}
+			}); gerr != nil {
+				n, err = 0, gerr
+			}
+			if n > 0 {
+				mmap.dirty.add(off, int64(n))
+			}
+			mmap.mu.RUnlock()
+			return n, err
+		}
+		mmap.mu.RUnlock()
+	}
+}
+
+// writePrivate copies b into the private pages, the private mapping can't grow,
+// so that the bytes beyond the file size aren't written.
+func (mmap *MemoryMap) writePrivate(b []byte, off int64) (int, error) {
+	if off < 0 {
+		return 0, errors.New("negative offset")
+	}
+
+	mmap.mu.RLock()
+	defer mmap.mu.RUnlock()
+
+	if mmap.closed() {
+		return 0, errors.New("mmap: closed")
+	}
+	if off >= mmap.size {
+		if len(b) == 0 {
+			return 0, nil
+		}
+		return 0, errors.New("mmap: write beyond the end of private mapping")
+	}
+
+	end := off + int64(len(b))
+	if end > mmap.size {
+		end = mmap.size
+	}
+
+	var n int
+	if gerr := mmap.guard(end, func() {
+		n = copy(mmap.data[off:end], b)
+	}); gerr != nil {
+		return 0, gerr
+	}
+	if n < len(b) {
+		return n, errors.New("mmap: write beyond the end of private mapping")
+	}
+
+	return n, nil
+}
+
+// Write writes len(b) bytes to the File at the current offset.
+// on writable and private mode, it's impled by WriteAt.
+func (mmap *MemoryMap) Write(b []byte) (int, error) {
+	mmap.cursor.Lock()
+	defer mmap.cursor.Unlock()
+
+	mmap.prevRune = -1
+
+	// pwrite isn't allowed on O_APPEND file
+	if !mmap.opt.MmapWritable && !mmap.opt.MmapPrivate && mmap.opt.Flag&os.O_APPEND != 0 {
+		n, err := mmap.File.Write(b)
+		if n > 0 {
+			end, serr := mmap.File.Seek(0, io.SeekCurrent)
+			if serr == nil {
+				serr = mmap.extend(end)
+			}
+			if err == nil {
+				err = serr
+			}
+			mmap.offset = end
+		}
+		return n, err
+	}
+
+	off := mmap.offset
+	if mmap.opt.Flag&os.O_APPEND != 0 {
+		size, err := mmap.refresh()
+		if err != nil {
+			return 0, err
+		}
+		off = size
+	}
+	n, err := mmap.WriteAt(b, off)
+	mmap.offset = off + int64(n)
+
+	return n, err
+}
+
+// Read reads up to len(b) bytes from the mapping at the current offset.
+// At end of file, Read returns 0, io.EOF.
+func (mmap *MemoryMap) Read(b []byte) (int, error) {
+	mmap.cursor.Lock()
+	defer mmap.cursor.Unlock()
+
+	mmap.prevRune = -1
+	n, err := mmap.ReadAt(b, mmap.offset)
+	mmap.offset += int64(n)
+	if n > 0 && err == io.EOF {
+		err = nil
+	}
+
+	return n, err
+}
+
+// ReadByte reads and returns the next byte from the mapping.
+func (mmap *MemoryMap) ReadByte() (byte, error) {
+	mmap.cursor.Lock()
+	defer mmap.cursor.Unlock()
+
+	mmap.prevRune = -1
+	var c [1]byte
+	if _, err := mmap.ReadAt(c[:], mmap.offset); err != nil {
+		return 0, err
+	}
+	mmap.offset++
+
+	return c[0], nil
+}
+
+// UnreadByte unreads the last byte, only the byte before the current offset can be unread.
+func (mmap *MemoryMap) UnreadByte() error {
+	mmap.cursor.Lock()
+	defer mmap.cursor.Unlock()
+
+	if mmap.offset <= 0 {
+		return errors.New("mmap: at beginning of file")
+	}
+	mmap.prevRune = -1
+	mmap.offset--
+
+	return nil
+}
+
+// ReadRune reads a single UTF-8 encoded unicode character from the mapping.
+// If the bytes are not a valid UTF-8 encoding, it consumes one byte and returns U+FFFD.
+func (mmap *MemoryMap) ReadRune() (rune, int, error) {
+	mmap.cursor.Lock()
+	defer mmap.cursor.Unlock()
+
+	mmap.prevRune = -1
+	var b [utf8.UTFMax]byte
+	nr, err := mmap.ReadAt(b[:], mmap.offset)
+	if nr == 0 {
+		return 0, 0, err
+	}
+	if err != nil && err != io.EOF {
+		return 0, 0, err
+	}
+
+	r, n := utf8.DecodeRune(b[:nr])
+	mmap.prevRune = mmap.offset
+	mmap.offset += int64(n)
+
+	return r, n, nil
+}
+
+// UnreadRune unreads the last rune, the previous operation must be ReadRune.
+func (mmap *MemoryMap) UnreadRune() error {
+	mmap.cursor.Lock()
+	defer mmap.cursor.Unlock()
+
+	if mmap.prevRune < 0 {
+		return errors.New("mmap: previous operation was not ReadRune")
+	}
+	mmap.offset = mmap.prevRune
+	mmap.prevRune = -1
+
+	return nil
+}
+
+// Seek sets the offset for the next Read or Write to offset, interpreted
+// according to whence: 0 means relative to the origin of the file, 1 means
+// relative to the current offset, and 2 means relative to the end,
+// SeekData and SeekHole mean the next data or hole at or after the offset.
+// the offset is kept by MemoryMap.
+func (mmap *MemoryMap) Seek(offset int64, whence int) (int64, error) {
+	mmap.cursor.Lock()
+	defer mmap.cursor.Unlock()
+
+	var nOffset int64
+	switch whence {
+	case io.SeekStart:
+		nOffset = offset
+	case io.SeekCurrent:
+		nOffset = mmap.offset + offset
+	case io.SeekEnd:
+		// the file may be extended by another handle or process
+		size, err := mmap.refresh()
+		if err != nil {
+			return 0, err
+		}
+		nOffset = size + offset
+	case SeekData, SeekHole:
+		// the file offset isn't used by MemoryMap, it's safe to move it
+		n, err := mmap.File.Seek(offset, whence)
+		if err != nil {
+			return 0, err
+		}
+		nOffset = n
+	default:
+		return 0, errors.New("mmap: invalid whence")
+	}
+	if nOffset < 0 {
+		return 0, errors.New("mmap: negative position")
+	}
+
+	mmap.prevRune = -1
+	mmap.offset = nOffset
+	return nOffset, nil
+}
+
+// WriteTo writes the mapping from the current offset to the end of file to w.
+// It implements the io.WriterTo interface.
+func (mmap *MemoryMap) WriteTo(w io.Writer) (int64, error) {
+	mmap.cursor.Lock()
+	defer mmap.cursor.Unlock()
+
+	mmap.prevRune = -1
+	n, err := mmap.SendTo(w, mmap.offset, math.MaxInt64-mmap.offset)
+	mmap.offset += n
+
+	return n, err
+}
+
+// SendTo writes n bytes of the mapping from off to w, it stops at the end of file.
+// the mapped pages are moved into the pipe by vmsplice, other writers are written
+// from the mapping directly without an intermediate buffer.
+// It returns the number of bytes written.
+func (mmap *MemoryMap) SendTo(w io.Writer, off, n int64) (sent int64, err error) {
+	if off < 0 || n < 0 {
+		return 0, errors.New("mmap: invalid range")
+	}
+
+	// the file may be extended by another handle or process
+	if n > mmap.fileSize()-off {
+		if _, err := mmap.refresh(); err != nil {
+			return 0, err
+		}
+	}
+
+	for sent < n {
+		mmap.mu.RLock()
+		if mmap.closed() {
+			mmap.mu.RUnlock()
+			return sent, errors.New("mmap: closed")
+		}
+		start := off + sent
+		if start >= mmap.size {
+			mmap.mu.RUnlock()
+			return sent, nil
+		}
+		end := start + mmapWriteToChunk
+		if end > mmap.size {
+			end = mmap.size
+		}
+		if end-start > n-sent {
+			end = start + n - sent
+		}
+
+		// pin the chunk like Slice, the mapping isn't unmapped by remap or Close
+		// until the chunk is released
+		var chunk []byte
+		var win *window
+		ws := mmap.win
+		if ws != nil {
+			if win, err = ws.acquire(start); err != nil {
+				mmap.mu.RUnlock()
+				return sent, err
+			}
+			if wend := win.off + int64(len(win.data)); end > wend {
+				end = wend
+			}
+			chunk = win.data[start-win.off : end-win.off]
+		} else {
+			atomic.AddInt64(&mmap.refs, 1)
+			chunk = mmap.data[start:end:end]
+		}
+
+		// don't hold the read lock during the write, otherwise a slow writer
+		// blocks the remap, Truncate and Close.
+		mmap.mu.RUnlock()
+
+		var nw int
+		if gerr := mmap.guard(end, func() {
+			nw, err = writeMapped(w, chunk)
+		}); gerr != nil {
+			nw, err = 0, gerr
+		}
+		var rerr error
+		if win != nil {
+			rerr = ws.release(win)
+		} else {
+			rerr = mmap.Release(chunk)
+		}
+		if err == nil {
+			err = rerr
+		}
+
+		sent += int64(nw)
+		if err != nil {
+			return sent, err
+		}
+		if nw < len(chunk) {
+			return sent, io.ErrShortWrite
+		}
+	}
+
+	return sent, nil
+}
+
+// closed returns whether the MemoryMap is closed, the caller must hold the lock.
+func (mmap *MemoryMap) closed() bool {
+	if mmap.win != nil {
+		return mmap.win.closed()
+	}
+	return mmap.data == nil
+}
+
+// fileSize returns the tracked file size.
+func (mmap *MemoryMap) fileSize() int64 {
+	mmap.mu.RLock()
+	defer mmap.mu.RUnlock()
+	return mmap.size
+}
+
+// refresh returns the real file size, the tracked size is grown and the file is remapped
+// if the file has been extended by another handle or process.
+// the private mapping keeps the size at open, the tracked size is returned.
+func (mmap *MemoryMap) refresh() (int64, error) {
+	mmap.mu.RLock()
+	closed := mmap.closed()
+	mmap.mu.RUnlock()
+	if closed {
+		return 0, errors.New("mmap: closed")
+	}
+	if mmap.opt.MmapPrivate {
+		return mmap.fileSize(), nil
+	}
+
+	stat, err := mmap.File.Stat()
+	if err != nil {
+		return 0, err
+	}
+	return stat.Size(), mmap.extend(stat.Size())
+}
+
+// Truncate changes the size of the file, the file is remapped if it grows beyond the mapping.
+// the private mapping can't change the file.
+func (mmap *MemoryMap) Truncate(size int64) error {
+	if mmap.opt.MmapPrivate {
+		return ErrPrivateMapping
+	}
+
+	mmap.mu.Lock()
+	defer mmap.mu.Unlock()
+
+	if mmap.closed() {
+		return errors.New("mmap: closed")
+	}
+	if err := mmap.File.Truncate(size); err != nil {
+		return err
+	}
+	return mmap.resize(size)
+}
+
+// grow extends the file to at least end bytes,
+// and remaps the file if the mapping can't cover it.
+func (mmap *MemoryMap) grow(end int64) error {
+	mmap.mu.RLock()
+	covered := end <= mmap.size
+	mmap.mu.RUnlock()
+	if covered {
+		return nil
+	}
+
+	mmap.mu.Lock()
+	defer mmap.mu.Unlock()
+
+	if mmap.closed() {
+		return errors.New("mmap: closed")
+	}
+	if end <= mmap.size {
+		return nil
+	}
+
+	// don't shrink the file extended by another handle or process
+	stat, err := mmap.File.Stat()
+	if err != nil {
+		return err
+	}
+	if stat.Size() >= end {
+		return mmap.resize(stat.Size())
+	}
+	if err := mmap.File.Truncate(end); err != nil {
+		return err
+	}
+	return mmap.resize(end)
+}
+
+// extend updates the file size after it has been written up to end,
+// and remaps the file if the mapping can't cover it.
+func (mmap *MemoryMap) extend(end int64) error {
+	mmap.mu.RLock()
+	covered := end <= mmap.size
+	mmap.mu.RUnlock()
+	if covered {
+		return nil
+	}
+
+	mmap.mu.Lock()
+	defer mmap.mu.Unlock()
+
+	if mmap.closed() {
+		return errors.New("mmap: closed")
+	}
+	if end <= mmap.size {
+		return nil
+	}
+	return mmap.resize(end)
+}
+
+// resize sets the file size, the mapping is doubled until it covers the file.
+// the windows needn't to be remapped, they are mapped on demand.
+// the caller must hold the write lock.
+func (mmap *MemoryMap) resize(size int64) error {
+	if mmap.win != nil {
+		mmap.size = size
+		return nil
+	}
+
+	locked := mmap.size
+	if size > int64(len(mmap.data)) {
+		length := int64(len(mmap.data))
+		if length == 0 {
+			length = size
+		}
+		for length < size {
+			length <<= 1
+		}
+
+		data, err := mmap.remap(int(length))
+		if err != nil {
+			return err
+		}
+		// the new address isn't locked, eg: the retired mapping or darwin,
+		// which maps the file again
+		if len(mmap.data) == 0 || &data[0] != &mmap.data[0] {
+			locked = 0
+		}
+		mmap.data = data
+
+		if err := mmap.advise(); err != nil {
+			return err
+		}
+	}
+
+	mmap.size = size
+	if size > locked {
+		return mmap.lock(locked)
+	}
+	return nil
+}
+
+// remap maps the file with the new length, the borrowed mapping can't be moved,
+// so that it's retired and the file is mapped to a new address.
+// the caller must hold the write lock.
+func (mmap *MemoryMap) remap(length int) ([]byte, error) {
+	if atomic.LoadInt64(&mmap.refs) == 0 {
+		// the old mapping is still valid if remap fails
+		return remap(mmap.File, mmap.data, length, mmap.opt)
+	}
+
+	data, err := mmapFile(mmap.File, 0, length, mmap.opt)
+	if err != nil {
+		return nil, err
+	}
+	mmap.retire()
+	return data, nil
+}
+
+// advise applies the MmapAdvice access pattern hint to the whole mapping.
+// the caller must hold the write lock or own the MemoryMap exclusively.
+func (mmap *MemoryMap) advise() error {
+	if mmap.opt.MmapAdvice == MadvNormal {
+		return nil
+	}
+	return MadviseWith(mmap.data, mmap.opt.MmapAdvice)
+}
+
+// lock locks the pages of file from the offset to the end of file if MmapLock is set,
+// the pages beyond the end of file can't be locked.
+// the caller must hold the write lock or own the MemoryMap exclusively.
+func (mmap *MemoryMap) lock(off int64) error {
+	if !mmap.opt.MmapLock {
+		return nil
+	}
+
+	start, end := pageRange(off, mmap.size-off, len(mmap.data))
+	if start >= end {
+		return nil
+	}
+	return Lock(mmap.data[start:end])
+}
+
+// Advise advises the kernel about the access pattern of the file range [off, off+n),
+// the range is extended to the page boundaries.
+// the windowed mapping only supports the MmapAdvice applied to every window.
+func (mmap *MemoryMap) Advise(off, n int64, advice MadviseMode) error {
+	mmap.mu.RLock()
+	defer mmap.mu.RUnlock()
+
+	if mmap.win != nil {
+		return ErrNotSupported
+	}
+	if mmap.data == nil {
+		return errors.New("mmap: closed")
+	}
+	if off < 0 || n < 0 || off+n > int64(len(mmap.data)) {
+		return errors.New("mmap: invalid range")
+	}
+
+	start, end := pageRange(off, n, len(mmap.data))
+	if start >= end {
+		return nil
+	}
+	return MadviseWith(mmap.data[start:end], advice)
+}
+
+// pageRange extends the range [off, off+n) to the page boundaries and limits it within max.
+func pageRange(off, n int64, max int) (int, int) {
+	pageSize := int64(os.Getpagesize())
+	start := off &^ (pageSize - 1)
+	end := (off + n + pageSize - 1) &^ (pageSize - 1)
+	if end > int64(max) {
+		end = int64(max)
+	}
+	return int(start), int(end)
+}
+
 // FLock a file lock is a recommended lock.
 // if file lock not init, we will init once
 func (mmap *MemoryMap) FLock() (err error) {
@@ -97,16 +814,31 @@ func (mmap *MemoryMap) FUnlock() error {
 
 // Close closes the File
 func (mmap *MemoryMap) Close() error {
+	mmap.mu.Lock()
+	defer mmap.mu.Unlock()
+
 	if mmap.File != nil {
 		mmap.File.Close()
 	}
+	if mmap.win != nil {
+		runtime.SetFinalizer(mmap, nil)
+		return mmap.win.close()
+	}
 	if mmap.data == nil {
 		return nil
 	}
 
 	data := mmap.data
-	mmap.data = nil
 	runtime.SetFinalizer(mmap, nil)
+
+	// the borrowed mapping is unmapped by the last Release
+	if atomic.LoadInt64(&mmap.refs) > 0 {
+		mmap.retire()
+		mmap.data = nil
+		return nil
+	}
+
+	mmap.data = nil
 	return Munmap(data)
 }
 
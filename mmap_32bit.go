@@ -0,0 +1,24 @@
+// +build linux,386 linux,arm linux,mips linux,mipsle
+
+package ioengine
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// mmap2PageSize the unit of the mmap2 offset, it's 4096 regardless of the page size.
+const mmap2PageSize = 4096
+
+// mmap calls the mmap2 syscall directly, the offset of the mmap syscall is limited
+// to 32 bits, mmap2 takes it in 4096-byte units. unlike unix.Mmap the mapping
+// isn't tracked by address, so that it can be moved by mremap.
+func mmap(fd int, offset int64, length int, prot int, flags int) ([]byte, error) {
+	addr, _, errno := unix.Syscall6(unix.SYS_MMAP2, 0, uintptr(length),
+		uintptr(prot), uintptr(flags), uintptr(fd), uintptr(offset/mmap2PageSize))
+	if errno != 0 {
+		return nil, os.NewSyscallError("MMAP2", errno)
+	}
+	return mapped(addr, length), nil
+}
@@ -0,0 +1,20 @@
+// +build linux,!386,!arm,!mips,!mipsle darwin,amd64 darwin,arm64
+
+package ioengine
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// mmap calls the mmap syscall directly, unlike unix.Mmap the mapping
+// isn't tracked by address, so that it can be moved by mremap.
+func mmap(fd int, offset int64, length int, prot int, flags int) ([]byte, error) {
+	addr, _, errno := unix.Syscall6(unix.SYS_MMAP, 0, uintptr(length),
+		uintptr(prot), uintptr(flags), uintptr(fd), uintptr(offset))
+	if errno != 0 {
+		return nil, os.NewSyscallError("MMAP", errno)
+	}
+	return mapped(addr, length), nil
+}
@@ -0,0 +1,34 @@
+// +build darwin
+
+package ioengine
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// mapPopulate darwin doesn't support MAP_POPULATE.
+const mapPopulate = 0
+
+// madviseFlags translates MadviseMode to the darwin madvise advice,
+// MadvHugePage and MadvCold aren't supported.
+var madviseFlags = map[MadviseMode]int{
+	MadvNormal:     unix.MADV_NORMAL,
+	MadvSequential: unix.MADV_SEQUENTIAL,
+	MadvRandom:     unix.MADV_RANDOM,
+	MadvWillNeed:   unix.MADV_WILLNEED,
+	MadvDontNeed:   unix.MADV_DONTNEED,
+	MadvPageOut:    unix.MADV_PAGEOUT,
+}
+
+// remap resizes the mapping of fd, darwin has no mremap,
+// so that the file is mapped again and the old mapping is unmapped.
+func remap(fd *os.File, b []byte, length int, opt Options) ([]byte, error) {
+	data, err := mmapFile(fd, 0, length, opt)
+	if err != nil {
+		return nil, err
+	}
+	Munmap(b)
+	return data, nil
+}
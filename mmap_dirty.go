@@ -0,0 +1,234 @@
+package ioengine
+
+import (
+	"errors"
+	"math"
+	"os"
+	"sync"
+)
+
+// span the file range [start, end)
+type span struct {
+	start int64
+	end   int64
+}
+
+// dirtySpans tracks the modified pages of the mapping,
+// the spans are page aligned, sorted and merged.
+type dirtySpans struct {
+	spans []span
+	mu    sync.Mutex
+}
+
+// add marks the file range [off, off+n) dirty, it's extended to the page boundaries.
+func (d *dirtySpans) add(off, n int64) {
+	pageSize := int64(os.Getpagesize())
+	start := off &^ (pageSize - 1)
+	end := (off + n + pageSize - 1) &^ (pageSize - 1)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	// find the first span which can be merged or is after the range
+	i := 0
+	for i < len(d.spans) && d.spans[i].end < start {
+		i++
+	}
+	j := i
+	for j < len(d.spans) && d.spans[j].start <= end {
+		if d.spans[j].start < start {
+			start = d.spans[j].start
+		}
+		if d.spans[j].end > end {
+			end = d.spans[j].end
+		}
+		j++
+	}
+
+	spans := append([]span{}, d.spans[:i]...)
+	spans = append(spans, span{start, end})
+	d.spans = append(spans, d.spans[j:]...)
+}
+
+// take removes and returns the dirty spans within the file range [start, end).
+func (d *dirtySpans) take(start, end int64) []span {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	var taken, kept []span
+	for _, s := range d.spans {
+		if s.end <= start || s.start >= end {
+			kept = append(kept, s)
+			continue
+		}
+		if s.start < start {
+			kept = append(kept, span{s.start, start})
+		}
+		if s.end > end {
+			kept = append(kept, span{end, s.end})
+		}
+		taken = append(taken, span{max64(s.start, start), min64(s.end, end)})
+	}
+	d.spans = kept
+
+	return taken
+}
+
+// list returns a copy of the dirty spans.
+func (d *dirtySpans) list() []span {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]span{}, d.spans...)
+}
+
+// Sync commits the current contents of the file to stable storage.
+// on writable mode, only the modified pages are flushed by msync before fsync.
+// on private mode, it returns ErrPrivateMapping.
+func (mmap *MemoryMap) Sync() error {
+	if mmap.opt.MmapPrivate {
+		return ErrPrivateMapping
+	}
+	if mmap.opt.MmapWritable {
+		if err := mmap.syncDirty(0, math.MaxInt64); err != nil {
+			return err
+		}
+	}
+	return mmap.File.Sync()
+}
+
+// DataSync likes Sync, but flushes the file by fdatasync after msync.
+func (mmap *MemoryMap) DataSync() error {
+	if mmap.opt.MmapPrivate {
+		return ErrPrivateMapping
+	}
+	if mmap.opt.MmapWritable {
+		if err := mmap.syncDirty(0, math.MaxInt64); err != nil {
+			return err
+		}
+	}
+	return fdatasync(mmap.File)
+}
+
+// SyncRange writes back the file range [off, off+n) by flags, n == 0 means to the end of file.
+// on writable mode, the modified pages within the range are flushed by msync first,
+// they are waited only if SyncRangeWaitAfter is set, the range is extended to the page boundaries.
+// It doesn't flush the file metadata.
+func (mmap *MemoryMap) SyncRange(off, n int64, flags SyncRangeFlag) error {
+	if off < 0 || n < 0 {
+		return errors.New("mmap: invalid range")
+	}
+	if mmap.opt.MmapPrivate {
+		return ErrPrivateMapping
+	}
+	if mmap.opt.MmapWritable {
+		end := int64(math.MaxInt64)
+		if n > 0 && off+n > off {
+			end = off + n
+		}
+		if err := mmap.flushDirty(off, end, flags&SyncRangeWaitAfter == 0); err != nil {
+			return err
+		}
+	}
+	return syncFileRange(mmap.File, off, n, flags)
+}
+
+// FlushAsync schedules the modified pages to be written back by msync with MS_ASYNC,
+// it returns without waiting, the pages are still flushed by the next Sync.
+func (mmap *MemoryMap) FlushAsync() error {
+	if mmap.opt.MmapPrivate {
+		return ErrPrivateMapping
+	}
+	if !mmap.opt.MmapWritable {
+		return nil
+	}
+	return mmap.flushDirty(0, math.MaxInt64, true)
+}
+
+// flushDirty flushes the dirty spans within the file range [start, end) by msync,
+// on async, the spans are only scheduled and kept dirty until they're synced.
+func (mmap *MemoryMap) flushDirty(start, end int64, async bool) error {
+	if !async {
+		return mmap.syncDirty(start, end)
+	}
+
+	mmap.mu.RLock()
+	defer mmap.mu.RUnlock()
+
+	if mmap.closed() {
+		return errors.New("mmap: closed")
+	}
+	for _, s := range mmap.dirty.list() {
+		if s.end <= start || s.start >= end {
+			continue
+		}
+		if err := mmap.msync(s, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// syncDirty flushes the dirty spans within the file range [start, end) by msync,
+// the range is extended to the page boundaries,
+// the spans which fail to be flushed are marked dirty again.
+func (mmap *MemoryMap) syncDirty(start, end int64) error {
+	pageSize := int64(os.Getpagesize())
+	start &^= pageSize - 1
+	if end < math.MaxInt64-pageSize {
+		end = (end + pageSize - 1) &^ (pageSize - 1)
+	}
+
+	mmap.mu.RLock()
+	defer mmap.mu.RUnlock()
+
+	if mmap.closed() {
+		return errors.New("mmap: closed")
+	}
+
+	spans := mmap.dirty.take(start, end)
+	for i, s := range spans {
+		if err := mmap.msync(s, false); err != nil {
+			for _, s := range spans[i:] {
+				mmap.dirty.add(s.start, s.end-s.start)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// msync flushes the mapped pages of the span within the file size.
+// the caller must hold the lock.
+func (mmap *MemoryMap) msync(s span, async bool) error {
+	if s.end > mmap.size {
+		s.end = (mmap.size + int64(os.Getpagesize()) - 1) &^ int64(os.Getpagesize()-1)
+	}
+	if s.start >= s.end {
+		return nil
+	}
+	if mmap.win != nil {
+		return mmap.win.sync(s.start, s.end, async)
+	}
+
+	if s.end > int64(len(mmap.data)) {
+		s.end = int64(len(mmap.data))
+	}
+	if async {
+		return SyncAsync(mmap.data[s.start:s.end])
+	}
+	return Sync(mmap.data[s.start:s.end])
+}
+
+func min64(a, b int64) int64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max64(a, b int64) int64 {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,68 @@
+package ioengine
+
+import (
+	"os"
+	"testing"
+)
+
+func TestDirtySpans(t *testing.T) {
+	pageSize := int64(os.Getpagesize())
+
+	var d dirtySpans
+	d.add(1, 1)
+	d.add(4*pageSize, pageSize)
+	d.add(2*pageSize-1, 2)
+	if spans := d.list(); len(spans) != 2 || spans[0] != (span{0, 3 * pageSize}) || spans[1] != (span{4 * pageSize, 5 * pageSize}) {
+		t.Fatalf("add: unmatched spans %v", spans)
+	}
+
+	// merge the adjacent spans
+	d.add(3*pageSize, 1)
+	if spans := d.list(); len(spans) != 1 || spans[0] != (span{0, 5 * pageSize}) {
+		t.Fatalf("merge: unmatched spans %v", spans)
+	}
+
+	taken := d.take(pageSize, 2*pageSize)
+	if len(taken) != 1 || taken[0] != (span{pageSize, 2 * pageSize}) {
+		t.Fatalf("take: unmatched spans %v", taken)
+	}
+	if spans := d.list(); len(spans) != 2 || spans[0] != (span{0, pageSize}) || spans[1] != (span{2 * pageSize, 5 * pageSize}) {
+		t.Fatalf("take: unmatched remaining spans %v", spans)
+	}
+}
+
+func TestMmapSyncRange(t *testing.T) {
+	fd, err := NewWritableMemoryMap()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fd.Close()
+
+	pageSize := int64(os.Getpagesize())
+	if _, err := fd.WriteAt([]byte("hello"), 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fd.WriteAt([]byte("world"), 3*pageSize); err != nil {
+		t.Fatal(err)
+	}
+	if spans := fd.dirty.list(); len(spans) != 2 {
+		t.Fatalf("write: unmatched dirty spans %v", spans)
+	}
+
+	if err := fd.SyncRange(3*pageSize+1, 1, SyncRangeWaitBefore|SyncRangeWrite|SyncRangeWaitAfter); err != nil {
+		t.Fatal(err)
+	}
+	if spans := fd.dirty.list(); len(spans) != 1 || spans[0] != (span{0, pageSize}) {
+		t.Fatalf("syncRange: unmatched dirty spans %v", spans)
+	}
+
+	if err := fd.FlushAsync(); err != nil {
+		t.Fatal(err)
+	}
+	if err := fd.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if spans := fd.dirty.list(); len(spans) != 0 {
+		t.Fatalf("sync: unmatched dirty spans %v", spans)
+	}
+}
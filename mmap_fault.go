@@ -0,0 +1,63 @@
+package ioengine
+
+import (
+	"errors"
+	"runtime"
+	"runtime/debug"
+	"strings"
+)
+
+var (
+	// ErrFileTruncated the mapped file is truncated underneath, eg: by another process.
+	ErrFileTruncated = errors.New("mmap: file truncated")
+
+	// ErrUnexpectedFault the mapping access faults but the file isn't truncated, eg: IO error.
+	ErrUnexpectedFault = errors.New("mmap: unexpected fault")
+)
+
+// guard runs fn which accesses the file range [0, end) by mapping,
+// the SIGBUS on accessing the pages beyond the end of file is turned into
+// ErrFileTruncated instead of crashing the whole process.
+func (mmap *MemoryMap) guard(end int64, fn func()) (err error) {
+	old := debug.SetPanicOnFault(true)
+	defer func() {
+		debug.SetPanicOnFault(old)
+
+		r := recover()
+		if r == nil {
+			return
+		}
+		if !isFault(r) {
+			panic(r)
+		}
+		err = mmap.faultError(end)
+	}()
+
+	fn()
+	return nil
+}
+
+// faultError re-checks the file size after the mapping access faults.
+func (mmap *MemoryMap) faultError(end int64) error {
+	stat, err := mmap.File.Stat()
+	if err != nil {
+		return err
+	}
+	if stat.Size() < end {
+		return ErrFileTruncated
+	}
+	return ErrUnexpectedFault
+}
+
+// isFault returns whether the panic is raised by a memory fault with SetPanicOnFault,
+// the fault error carries the fault address since go1.17.
+func isFault(r interface{}) bool {
+	re, ok := r.(runtime.Error)
+	if !ok {
+		return false
+	}
+	if _, ok := re.(interface{ Addr() uintptr }); ok {
+		return true
+	}
+	return strings.Contains(re.Error(), "unexpected fault address")
+}
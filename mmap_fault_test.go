@@ -0,0 +1,45 @@
+package ioengine
+
+import (
+	"bytes"
+	"os"
+	"testing"
+)
+
+func TestMmapTruncatedUnderneath(t *testing.T) {
+	fd, err := NewMemoryMapWithSize(BlockSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fd.Close()
+
+	pageSize := os.Getpagesize()
+	if _, err := fd.WriteAt(bytes.Repeat([]byte("a"), 4*pageSize), 0); err != nil {
+		t.Fatal(err)
+	}
+
+	// truncate the mapped file from a second handle
+	other, err := os.OpenFile(fd.Name(), os.O_RDWR, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer other.Close()
+	if err := other.Truncate(0); err != nil {
+		t.Fatal(err)
+	}
+
+	b := make([]byte, 16)
+	if _, err := fd.ReadAt(b, int64(2*pageSize)); err != ErrFileTruncated {
+		t.Fatalf("read truncated pages should return ErrFileTruncated: %v", err)
+	}
+	if _, err := fd.Seek(int64(3*pageSize), 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fd.ReadByte(); err != ErrFileTruncated {
+		t.Fatalf("readByte truncated pages should return ErrFileTruncated: %v", err)
+	}
+	var buf bytes.Buffer
+	if _, err := fd.WriteTo(&buf); err != ErrFileTruncated {
+		t.Fatalf("writeTo truncated pages should return ErrFileTruncated: %v", err)
+	}
+}
@@ -0,0 +1,53 @@
+// +build linux
+
+package ioengine
+
+import (
+	"os"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	// mremapMayMove permits the kernel to relocate the mapping to a new virtual address.
+	mremapMayMove = 0x1
+
+	// mapPopulate prefaults page tables for the mapping.
+	mapPopulate = unix.MAP_POPULATE
+
+	// madvCold and madvPageOut are added on linux v5.4
+	madvCold    = 0x14
+	madvPageOut = 0x15
+)
+
+// madviseFlags translates MadviseMode to the linux madvise advice.
+var madviseFlags = map[MadviseMode]int{
+	MadvNormal:     unix.MADV_NORMAL,
+	MadvSequential: unix.MADV_SEQUENTIAL,
+	MadvRandom:     unix.MADV_RANDOM,
+	MadvWillNeed:   unix.MADV_WILLNEED,
+	MadvDontNeed:   unix.MADV_DONTNEED,
+	MadvHugePage:   unix.MADV_HUGEPAGE,
+	MadvCold:       madvCold,
+	MadvPageOut:    madvPageOut,
+}
+
+// Mremap expands or shrinks the mapped slice, it may be moved to a new address.
+// the slice must be returned by Mmap, the old slice is invalid after Mremap.
+func Mremap(b []byte, length int) ([]byte, error) {
+	if len(b) == 0 {
+		return nil, unix.EINVAL
+	}
+	addr, _, errno := unix.Syscall6(unix.SYS_MREMAP, uintptr(unsafe.Pointer(&b[0])),
+		uintptr(len(b)), uintptr(length), mremapMayMove, 0, 0)
+	if errno != 0 {
+		return nil, os.NewSyscallError("MREMAP", errno)
+	}
+	return mapped(addr, length), nil
+}
+
+// remap resizes the mapping of fd by mremap.
+func remap(fd *os.File, b []byte, length int, opt Options) ([]byte, error) {
+	return Mremap(b, length)
+}
@@ -0,0 +1,323 @@
+package ioengine
+
+import (
+	"container/list"
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"sync/atomic"
+)
+
+// defaultMmapPoolMaxMaps default max number of mappings of MmapPool
+const defaultMmapPoolMaxMaps = 8192
+
+// poolMapping a mapping of the whole pooled file.
+type poolMapping struct {
+	// refs the number of readers pinning the mapping, it's updated atomically,
+	// it's only increased under the lock while the mapping is in lru.
+	refs int32
+
+	// evicted whether the mapping is removed from lru, it's updated atomically,
+	// the evicted mapping is unmapped when the last reader releases it.
+	evicted int32
+
+	// unmapped the mapping is unmapped by either remove or the last release, it's guarded by the lock.
+	unmapped bool
+
+	file *PoolFile
+	data []byte
+}
+
+// MmapPool maps many small immutable files on demand with a global budget.
+// the file is mapped on first ReadAt and its fd is closed once mapped,
+// the least recently used mappings are unmapped to keep the number of mappings
+// and the mapped bytes within the budget, they are mapped again on next ReadAt,
+// so that neither vm.max_map_count nor the fd limit is exhausted.
+// the pooled files must not be modified or truncated while they are mapped.
+type MmapPool struct {
+	// maxMaps the max number of mappings
+	maxMaps int
+
+	// maxBytes the max mapped bytes, 0 means no limit
+	maxBytes int64
+
+	// bytes the mapped bytes
+	bytes int64
+
+	// lru the mappings, the front is the most recently used
+	lru *list.List
+
+	mu sync.Mutex
+}
+
+// PoolFile an immutable file opened by MmapPool.
+type PoolFile struct {
+	pool *MmapPool
+	name string
+	size int64
+
+	// elem the element of the current mapping in lru, nil if it isn't mapped
+	elem *list.Element
+
+	// mapping it's closed once the reader mapping the file finishes, the other readers
+	// of the file wait for it instead of mapping the file again.
+	mapping chan struct{}
+
+	closed bool
+}
+
+// NewMmapPool returns a MmapPool, maxMaps is default 8192 if it's not greater than 0,
+// maxBytes 0 means the mapped bytes aren't limited.
+// a file larger than maxBytes is still mapped after all other mappings are evicted.
+func NewMmapPool(maxMaps int, maxBytes int64) *MmapPool {
+	if maxMaps <= 0 {
+		maxMaps = defaultMmapPoolMaxMaps
+	}
+	return &MmapPool{
+		maxMaps:  maxMaps,
+		maxBytes: maxBytes,
+		lru:      list.New(),
+	}
+}
+
+// Open returns the pooled file, the file isn't mapped until the first ReadAt.
+func (p *MmapPool) Open(name string) (*PoolFile, error) {
+	stat, err := os.Stat(name)
+	if err != nil {
+		return nil, err
+	}
+	if !stat.Mode().IsRegular() {
+		return nil, errors.New("mmap pool: not a regular file")
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.lru == nil {
+		return nil, errors.New("mmap pool: closed")
+	}
+	return &PoolFile{pool: p, name: name, size: stat.Size()}, nil
+}
+
+// Len returns the number of mappings, excluding the evicted mappings pinned by readers.
+func (p *MmapPool) Len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.lru == nil {
+		return 0
+	}
+	return p.lru.Len()
+}
+
+// Bytes returns the mapped bytes of the mappings in lru.
+func (p *MmapPool) Bytes() int64 {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.bytes
+}
+
+// acquire returns the mapping of the file and pins it, the file is mapped if it isn't
+// mapped yet, the lock isn't held while the file is opened and mapped.
+func (p *MmapPool) acquire(f *PoolFile) (*poolMapping, error) {
+	for {
+		p.mu.Lock()
+		if p.lru == nil {
+			p.mu.Unlock()
+			return nil, errors.New("mmap pool: closed")
+		}
+		if f.closed {
+			p.mu.Unlock()
+			return nil, errors.New("mmap pool: file closed")
+		}
+		if f.elem != nil {
+			p.lru.MoveToFront(f.elem)
+			m := f.elem.Value.(*poolMapping)
+			atomic.AddInt32(&m.refs, 1)
+			p.mu.Unlock()
+			return m, nil
+		}
+
+		// another reader is mapping the file
+		if wait := f.mapping; wait != nil {
+			p.mu.Unlock()
+			<-wait
+			continue
+		}
+		f.mapping = make(chan struct{})
+		p.mu.Unlock()
+
+		return p.mapFile(f)
+	}
+}
+
+// mapFile maps the file without holding the lock, then adds the mapping into lru
+// and wakes up the readers waiting for it.
+func (p *MmapPool) mapFile(f *PoolFile) (*poolMapping, error) {
+	var data []byte
+	fd, err := os.Open(f.name)
+	if err == nil {
+		// the mapping is still valid after the fd is closed
+		data, err = Mmap(fd, 0, int(f.size), false)
+		fd.Close()
+	}
+
+	p.mu.Lock()
+	close(f.mapping)
+	f.mapping = nil
+	if err == nil && (p.lru == nil || f.closed) {
+		err = errors.New("mmap pool: closed")
+	}
+	if err != nil {
+		p.mu.Unlock()
+		if data != nil {
+			Munmap(data)
+		}
+		return nil, err
+	}
+
+	m := &poolMapping{file: f, data: data, refs: 1}
+	f.elem = p.lru.PushFront(m)
+	p.bytes += int64(len(data))
+	unmaps := p.evict()
+	p.mu.Unlock()
+
+	munmapAll(unmaps)
+	return m, nil
+}
+
+// release unpins the mapping, the lock is only taken by the last reader
+// of the evicted mapping, which unmaps it.
+func (p *MmapPool) release(m *poolMapping) error {
+	if atomic.AddInt32(&m.refs, -1) > 0 || atomic.LoadInt32(&m.evicted) == 0 {
+		return nil
+	}
+
+	p.mu.Lock()
+	if m.unmapped || atomic.LoadInt32(&m.refs) > 0 {
+		p.mu.Unlock()
+		return nil
+	}
+	m.unmapped = true
+	p.mu.Unlock()
+
+	return Munmap(m.data)
+}
+
+// evict removes the least recently used mappings beyond the budget,
+// the most recently used mapping is always kept.
+// it returns the mappings to be unmapped after the lock is released.
+// the caller must hold the lock.
+func (p *MmapPool) evict() (unmaps [][]byte) {
+	for p.lru.Len() > 1 && (p.lru.Len() > p.maxMaps || (p.maxBytes > 0 && p.bytes > p.maxBytes)) {
+		if data := p.remove(p.lru.Back()); data != nil {
+			unmaps = append(unmaps, data)
+		}
+	}
+	return unmaps
+}
+
+// remove removes the mapping from lru, it returns the mapping to be unmapped after
+// the lock is released, or nil if it's pinned, which is unmapped by the last release.
+// the caller must hold the lock.
+func (p *MmapPool) remove(e *list.Element) []byte {
+	m := p.lru.Remove(e).(*poolMapping)
+	m.file.elem = nil
+	p.bytes -= int64(len(m.data))
+
+	atomic.StoreInt32(&m.evicted, 1)
+	if m.unmapped || atomic.LoadInt32(&m.refs) > 0 {
+		return nil
+	}
+	m.unmapped = true
+	return m.data
+}
+
+// munmapAll unmaps the mappings removed from lru, it returns the first error.
+func munmapAll(unmaps [][]byte) (err error) {
+	for _, data := range unmaps {
+		if e := Munmap(data); e != nil && err == nil {
+			err = e
+		}
+	}
+	return err
+}
+
+// Close unmaps all mappings, the pinned mappings are unmapped by the last reader.
+func (p *MmapPool) Close() error {
+	p.mu.Lock()
+	if p.lru == nil {
+		p.mu.Unlock()
+		return nil
+	}
+	var unmaps [][]byte
+	for p.lru.Len() > 0 {
+		if data := p.remove(p.lru.Front()); data != nil {
+			unmaps = append(unmaps, data)
+		}
+	}
+	p.lru = nil
+	p.mu.Unlock()
+
+	return munmapAll(unmaps)
+}
+
+// Name returns the name of the file.
+func (f *PoolFile) Name() string {
+	return f.name
+}
+
+// Size returns the size of the file when it's opened.
+func (f *PoolFile) Size() int64 {
+	return f.size
+}
+
+// ReadAt like any io.ReaderAt, clients can execute parallel ReadAt calls.
+// the file is mapped by the first ReadAt.
+func (f *PoolFile) ReadAt(b []byte, off int64) (int, error) {
+	if len(b) == 0 {
+		return 0, nil
+	}
+	if off < 0 {
+		return 0, errors.New("negative offset")
+	}
+	if off >= f.size {
+		return 0, io.EOF
+	}
+
+	m, err := f.pool.acquire(f)
+	if err != nil {
+		return 0, err
+	}
+	defer f.pool.release(m)
+
+	n := copy(b, m.data[off:])
+	if n < len(b) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// Close unmaps the file, the pinned mapping is unmapped by the last reader.
+func (f *PoolFile) Close() error {
+	p := f.pool
+
+	p.mu.Lock()
+	if f.closed {
+		p.mu.Unlock()
+		return nil
+	}
+	f.closed = true
+	if f.elem == nil || p.lru == nil {
+		p.mu.Unlock()
+		return nil
+	}
+	data := p.remove(f.elem)
+	p.mu.Unlock()
+
+	if data == nil {
+		return nil
+	}
+	return Munmap(data)
+}
@@ -0,0 +1,114 @@
+package ioengine
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sync"
+	"testing"
+)
+
+func TestMmapPool(t *testing.T) {
+	pool := NewMmapPool(3, 4*BlockSize)
+	defer pool.Close()
+
+	var files []*PoolFile
+	for i := 0; i < 10; i++ {
+		name := fmt.Sprintf("/tmp/mmap/pool-%d", i)
+		content := bytes.Repeat([]byte{byte('a' + i)}, (i%3+1)*BlockSize)
+		if err := ioutil.WriteFile(name, content, 0644); err != nil {
+			t.Fatal(err)
+		}
+		f, err := pool.Open(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, f)
+	}
+	if pool.Len() != 0 {
+		t.Fatal("open: the file should be mapped lazily")
+	}
+
+	for round := 0; round < 2; round++ {
+		for i, f := range files {
+			b := make([]byte, f.Size()+1)
+			n, err := f.ReadAt(b, 0)
+			if err != io.EOF || int64(n) != f.Size() {
+				t.Fatalf("read: %d, %v", n, err)
+			}
+			if !bytes.Equal(b[:n], bytes.Repeat([]byte{byte('a' + i)}, n)) {
+				t.Fatal("unmatched content")
+			}
+			if pool.Len() > 3 {
+				t.Fatalf("map count exceeds budget: %d", pool.Len())
+			}
+			if pool.Len() > 1 && pool.Bytes() > 4*BlockSize {
+				t.Fatalf("mapped bytes exceed budget: %d", pool.Bytes())
+			}
+		}
+	}
+
+	if err := files[9].Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := files[9].ReadAt(make([]byte, 1), 0); err == nil {
+		t.Fatal("read a closed file should fail")
+	}
+
+	if err := pool.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := files[0].ReadAt(make([]byte, 1), 0); err == nil {
+		t.Fatal("read a closed pool should fail")
+	}
+	for i := range files {
+		os.Remove(fmt.Sprintf("/tmp/mmap/pool-%d", i))
+	}
+}
+
+func TestMmapPoolConcurrent(t *testing.T) {
+	pool := NewMmapPool(2, 0)
+	defer pool.Close()
+
+	var files []*PoolFile
+	for i := 0; i < 4; i++ {
+		name := fmt.Sprintf("/tmp/mmap/pool-concurrent-%d", i)
+		defer os.Remove(name)
+		if err := ioutil.WriteFile(name, bytes.Repeat([]byte{byte('a' + i)}, BlockSize), 0644); err != nil {
+			t.Fatal(err)
+		}
+		f, err := pool.Open(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files = append(files, f)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			b := make([]byte, BlockSize)
+			for i := 0; i < 200; i++ {
+				idx := (g + i) % len(files)
+				n, err := files[idx].ReadAt(b, 0)
+				if err != nil || n != BlockSize || b[n-1] != byte('a'+idx) {
+					errs <- fmt.Errorf("read %d: %d, %v", idx, n, err)
+					return
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatal(err)
+	}
+	if pool.Len() > 2 {
+		t.Fatalf("map count exceeds budget: %d", pool.Len())
+	}
+}
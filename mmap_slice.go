@@ -0,0 +1,103 @@
+package ioengine
+
+import (
+	"errors"
+	"sync/atomic"
+	"unsafe"
+)
+
+// mapping an old mapping retired by remap or Close, which is still borrowed.
+type mapping struct {
+	data []byte
+	refs int64
+}
+
+// Slice returns the mapped bytes of the file range [off, off+n) without copy.
+// the slice is borrowed from the mapping, it must be released by Release,
+// the mapping won't be unmapped by remap or Close until the slice is released.
+// on windowed mode, the range must be within a window.
+// the slice is read-only, it must not be modified even if MmapWritable is set,
+// and it must not be accessed after the file is truncated below its range.
+func (mmap *MemoryMap) Slice(off int64, n int) ([]byte, error) {
+	if off < 0 || n < 0 {
+		return nil, errors.New("mmap: invalid range")
+	}
+
+	mmap.mu.RLock()
+	defer mmap.mu.RUnlock()
+
+	if mmap.closed() {
+		return nil, errors.New("mmap: closed")
+	}
+	end := off + int64(n)
+	if end > mmap.size {
+		return nil, errors.New("mmap: slice beyond end of file")
+	}
+	if n == 0 {
+		return []byte{}, nil
+	}
+	if mmap.win != nil {
+		return mmap.win.slice(off, n)
+	}
+
+	atomic.AddInt64(&mmap.refs, 1)
+	return mmap.data[off:end:end], nil
+}
+
+// Release returns the slice borrowed by Slice, it must not be accessed after Release.
+func (mmap *MemoryMap) Release(b []byte) error {
+	if len(b) == 0 {
+		return nil
+	}
+
+	// fast path, the slice is borrowed from the current mapping
+	mmap.mu.RLock()
+	if win := mmap.win; win != nil {
+		mmap.mu.RUnlock()
+		return win.releaseSlice(b)
+	}
+	if contains(mmap.data, b) {
+		atomic.AddInt64(&mmap.refs, -1)
+		mmap.mu.RUnlock()
+		return nil
+	}
+	mmap.mu.RUnlock()
+
+	mmap.retiredMu.Lock()
+	defer mmap.retiredMu.Unlock()
+
+	for i, m := range mmap.retired {
+		if !contains(m.data, b) {
+			continue
+		}
+		m.refs--
+		if m.refs > 0 {
+			return nil
+		}
+		mmap.retired = append(mmap.retired[:i], mmap.retired[i+1:]...)
+		return Munmap(m.data)
+	}
+
+	return errors.New("mmap: slice isn't borrowed")
+}
+
+// retire moves the current borrowed mapping to the retired list,
+// the caller must hold the write lock and replace the mapping.
+func (mmap *MemoryMap) retire() {
+	mmap.retiredMu.Lock()
+	mmap.retired = append(mmap.retired, &mapping{
+		data: mmap.data,
+		refs: atomic.SwapInt64(&mmap.refs, 0),
+	})
+	mmap.retiredMu.Unlock()
+}
+
+// contains returns whether b is a sub-slice of data.
+func contains(data, b []byte) bool {
+	if len(data) == 0 || len(b) == 0 {
+		return false
+	}
+	start := uintptr(unsafe.Pointer(&data[0]))
+	p := uintptr(unsafe.Pointer(&b[0]))
+	return p >= start && p+uintptr(len(b)) <= start+uintptr(len(data))
+}
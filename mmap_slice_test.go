@@ -0,0 +1,137 @@
+package ioengine
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"unsafe"
+)
+
+func TestMmapSlice(t *testing.T) {
+	fd, err := NewMemoryMapWithSize(BlockSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := fd.Write([]byte("hello world")); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := fd.Slice(6, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "world" {
+		t.Fatal("slice: unmatched content")
+	}
+	if _, err := fd.Slice(6, BlockSize); err == nil {
+		t.Fatal("slice beyond end of file should fail")
+	}
+
+	// remap while the slice is borrowed
+	if _, err := fd.WriteAt(bytes.Repeat([]byte("a"), 4*BlockSize), BlockSize); err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "world" {
+		t.Fatal("slice: unmatched content after remap")
+	}
+
+	nb, err := fd.Slice(4*BlockSize, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// close while the slices are borrowed
+	if err := fd.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "world" || string(nb) != "aaaa" {
+		t.Fatal("slice: unmatched content after close")
+	}
+
+	if err := fd.Release(b); err != nil {
+		t.Fatal(err)
+	}
+	if err := fd.Release(nb); err != nil {
+		t.Fatal(err)
+	}
+	if err := fd.Release(b); err == nil {
+		t.Fatal("release twice should fail")
+	}
+}
+
+// lockedSize returns the locked bytes of the mapping containing addr by /proc/self/smaps.
+func lockedSize(t *testing.T, addr uintptr) int64 {
+	data, err := ioutil.ReadFile("/proc/self/smaps")
+	if err != nil {
+		t.Skip("smaps isn't supported")
+	}
+
+	found := false
+	for _, line := range strings.Split(string(data), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		if r := strings.Split(fields[0], "-"); len(r) == 2 {
+			start, err1 := strconv.ParseUint(r[0], 16, 64)
+			end, err2 := strconv.ParseUint(r[1], 16, 64)
+			if err1 == nil && err2 == nil {
+				found = uint64(addr) >= start && uint64(addr) < end
+				continue
+			}
+		}
+		if found && fields[0] == "Locked:" && len(fields) > 1 {
+			kb, err := strconv.ParseInt(fields[1], 10, 64)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return kb << 10
+		}
+	}
+	t.Fatalf("mapping %x isn't found", addr)
+	return 0
+}
+
+func TestMmapSliceLock(t *testing.T) {
+	opt := DefaultOptions
+	opt.IOEngine = MMap
+	opt.MmapSize = BlockSize
+	opt.MmapWritable = true
+	opt.MmapLock = true
+	mmapID++
+	name := fmt.Sprintf("/tmp/mmap/%d", mmapID)
+	os.Remove(name)
+
+	fd, err := newMemoryMap(name, opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fd.Close()
+
+	// the locked pages end at the page boundary
+	if _, err := fd.WriteAt(bytes.Repeat([]byte("l"), BlockSize), 0); err != nil {
+		t.Fatal(err)
+	}
+	b, err := fd.Slice(0, 5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fd.Release(b)
+
+	// the borrowed mapping is retired, the file is mapped to a new address
+	if err := fd.Truncate(4 * BlockSize); err != nil {
+		t.Fatal(err)
+	}
+	fd.mu.RLock()
+	addr := uintptr(unsafe.Pointer(&fd.data[0]))
+	fd.mu.RUnlock()
+	// the first page is shared with the retired mapping, it's counted proportionally
+	if size := lockedSize(t, addr); size <= 3*BlockSize {
+		t.Fatalf("the first page isn't locked, locked size %d", size)
+	}
+}
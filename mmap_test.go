@@ -1,7 +1,12 @@
 package ioengine
 
 import (
+	"bytes"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sync"
 	"testing"
 )
 
@@ -16,6 +21,27 @@ func NewMemoryMap() (*MemoryMap, error) {
 	return newMemoryMap(name, opt)
 }
 
+func NewMemoryMapWithSize(size int) (*MemoryMap, error) {
+	opt := DefaultOptions
+	opt.IOEngine = MMap
+	opt.MmapSize = size
+	mmapID++
+	name := fmt.Sprintf("/tmp/mmap/%d", mmapID)
+	os.Remove(name)
+	return newMemoryMap(name, opt)
+}
+
+func NewWritableMemoryMap() (*MemoryMap, error) {
+	opt := DefaultOptions
+	opt.IOEngine = MMap
+	opt.MmapSize = BlockSize
+	opt.MmapWritable = true
+	mmapID++
+	name := fmt.Sprintf("/tmp/mmap/%d", mmapID)
+	os.Remove(name)
+	return newMemoryMap(name, opt)
+}
+
 // func TestWrite(t *testing.T) {
 // 	mmap, err := NewFileIO()
 // 	if err != nil {
@@ -52,3 +78,358 @@ func TestMmapWrite(t *testing.T) {
 	// t.Log(err)
 	// t.Log(string(b))
 }
+
+func TestMmapWritable(t *testing.T) {
+	fd, err := NewWritableMemoryMap()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fd.Close()
+
+	nw, err := fd.Write([]byte("hello world"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nw != 11 {
+		t.Fatal("write: short write")
+	}
+
+	// write beyond the mapping to grow and remap the file
+	nw, err = fd.WriteAt([]byte("mmap"), 2*BlockSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nw != 4 {
+		t.Fatal("writeAt: short write")
+	}
+
+	b := NewBuffers()
+	b.Write([]byte("append"))
+	nw, err = fd.Append(*b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nw != 6 {
+		t.Fatal("append: short write")
+	}
+
+	if err := fd.Sync(); err != nil {
+		t.Fatal(err)
+	}
+
+	stat, err := fd.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stat.Size() != 2*BlockSize+10 {
+		t.Fatalf("invalid file size: %d", stat.Size())
+	}
+
+	rb := make([]byte, 10)
+	if _, err := fd.File.ReadAt(rb, 2*BlockSize); err != nil {
+		t.Fatal(err)
+	}
+	if string(rb) != "mmapappend" {
+		t.Fatal("unmatched content")
+	}
+
+	rb = make([]byte, 11)
+	if _, err := fd.ReadAt(rb, 0); err != nil {
+		t.Fatal(err)
+	}
+	if string(rb) != "hello world" {
+		t.Fatal("unmatched content")
+	}
+}
+
+func TestMmapRemap(t *testing.T) {
+	fd, err := NewMemoryMapWithSize(BlockSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fd.Close()
+
+	// the bytes beyond the file size aren't readable
+	b := make([]byte, 16)
+	if _, err := fd.ReadAt(b, 0); err != io.EOF {
+		t.Fatal("read empty file should return EOF")
+	}
+
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			rb := make([]byte, BlockSize)
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				if _, err := fd.ReadAt(rb, 0); err != nil && err != io.EOF {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+
+	record := bytes.Repeat([]byte("a"), BlockSize)
+	for i := 0; i < 16; i++ {
+		bs := NewBuffers()
+		bs.Write(record)
+		if _, err := fd.Append(*bs); err != nil {
+			t.Fatal(err)
+		}
+	}
+	close(done)
+	wg.Wait()
+
+	// read the data beyond the initial mapping
+	nr, err := fd.ReadAt(b, 15*BlockSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nr != len(b) || !bytes.Equal(b, record[:len(b)]) {
+		t.Fatal("unmatched content")
+	}
+	if _, err := fd.ReadAt(b, 16*BlockSize-8); err != io.EOF {
+		t.Fatal("read beyond file size should return EOF")
+	}
+
+	if err := fd.Truncate(BlockSize); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fd.ReadAt(b, BlockSize); err != io.EOF {
+		t.Fatal("read after truncate should return EOF")
+	}
+}
+
+func TestMmapReadSeek(t *testing.T) {
+	fd, err := NewMemoryMapWithSize(BlockSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fd.Close()
+
+	if _, err := fd.Write([]byte("hello 世界")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fd.Seek(0, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+
+	b := make([]byte, 5)
+	nr, err := fd.Read(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nr != 5 || string(b) != "hello" {
+		t.Fatal("read: unmatched content")
+	}
+
+	c, err := fd.ReadByte()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c != ' ' {
+		t.Fatal("readByte: unmatched content")
+	}
+
+	r, size, err := fd.ReadRune()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r != '世' || size != 3 {
+		t.Fatal("readRune: unmatched content")
+	}
+	if err := fd.UnreadRune(); err != nil {
+		t.Fatal(err)
+	}
+
+	off, err := fd.Seek(0, io.SeekCurrent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if off != 6 {
+		t.Fatalf("seek: invalid offset %d", off)
+	}
+
+	var buf bytes.Buffer
+	nw, err := fd.WriteTo(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nw != 6 || buf.String() != "世界" {
+		t.Fatal("writeTo: unmatched content")
+	}
+
+	if _, err := fd.Read(b); err != io.EOF {
+		t.Fatal("read at end of file should return EOF")
+	}
+	if _, err := fd.Seek(-1, io.SeekStart); err == nil {
+		t.Fatal("seek to negative position should fail")
+	}
+}
+
+func TestMmapReadOtherWriter(t *testing.T) {
+	fd, err := NewMemoryMapWithSize(BlockSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fd.Close()
+
+	// the file is written by another handle after it's mapped
+	w, err := os.OpenFile(fd.path, os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	if _, err := w.Write([]byte("hello")); err != nil {
+		t.Fatal(err)
+	}
+
+	b := make([]byte, 10)
+	nr, err := fd.ReadAt(b, 0)
+	if err != io.EOF || nr != 5 || string(b[:nr]) != "hello" {
+		t.Fatalf("readAt: %d, %v", nr, err)
+	}
+
+	// beyond the initial mapping
+	if _, err := w.WriteAt(bytes.Repeat([]byte("w"), 2*BlockSize), 5); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	nw, err := fd.SendTo(&buf, 0, 3*BlockSize)
+	if err != nil || nw != 2*BlockSize+5 {
+		t.Fatalf("sendTo: %d, %v", nw, err)
+	}
+	if !bytes.HasPrefix(buf.Bytes(), []byte("hellow")) {
+		t.Fatal("sendTo: unmatched content")
+	}
+}
+
+func TestMmapAppendHandles(t *testing.T) {
+	for _, writable := range []bool{false, true} {
+		opt := DefaultOptions
+		opt.IOEngine = MMap
+		opt.MmapSize = BlockSize
+		opt.MmapWritable = writable
+		mmapID++
+		name := fmt.Sprintf("/tmp/mmap/%d", mmapID)
+		os.Remove(name)
+
+		a, err := newMemoryMap(name, opt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		b, err := newMemoryMap(name, opt)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := a.Append([][]byte{[]byte("AAAA")}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := b.Append([][]byte{[]byte("BBBB")}); err != nil {
+			t.Fatal(err)
+		}
+		if end, err := a.Seek(0, io.SeekEnd); err != nil || end != 8 {
+			t.Fatalf("seek end: %d, %v", end, err)
+		}
+		a.Close()
+		b.Close()
+
+		data, err := ioutil.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != "AAAABBBB" {
+			t.Fatalf("writable %v: unmatched appended data %q", writable, data)
+		}
+	}
+}
+
+func TestMmapAdvise(t *testing.T) {
+	opt := DefaultOptions
+	opt.IOEngine = MMap
+	opt.MmapSize = BlockSize
+	opt.MmapAdvice = MadvRandom
+	opt.MmapPopulate = true
+	opt.MmapLock = true
+	mmapID++
+	name := fmt.Sprintf("/tmp/mmap/%d", mmapID)
+	os.Remove(name)
+
+	fd, err := newMemoryMap(name, opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fd.Close()
+
+	// grow the file to lock the new pages
+	if err := fd.Truncate(4 * BlockSize); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, advice := range []MadviseMode{MadvSequential, MadvWillNeed, MadvNormal} {
+		if err := fd.Advise(1, BlockSize, advice); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := fd.Advise(0, 1<<40, MadvWillNeed); err == nil {
+		t.Fatal("advise out of mapping should fail")
+	}
+}
+
+func TestMmapPrivate(t *testing.T) {
+	mmapID++
+	name := fmt.Sprintf("/tmp/mmap/%d", mmapID)
+	os.Remove(name)
+	if err := ioutil.WriteFile(name, []byte("hello world"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	opt := DefaultOptions
+	opt.IOEngine = MMap
+	opt.Flag = os.O_RDONLY
+	opt.MmapSize = BlockSize
+	opt.MmapPrivate = true
+	fd, err := newMemoryMap(name, opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fd.Close()
+
+	if _, err := fd.WriteAt([]byte("HELLO"), 0); err != nil {
+		t.Fatal(err)
+	}
+	b := make([]byte, 11)
+	if _, err := fd.ReadAt(b, 0); err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "HELLO world" {
+		t.Fatalf("unmatched private content: %s", b)
+	}
+
+	// the private mapping can't grow the file
+	if n, err := fd.WriteAt([]byte("WORLD!"), 6); err == nil || n != 5 {
+		t.Fatalf("write beyond the end: %d, %v", n, err)
+	}
+	if err := fd.Sync(); err != ErrPrivateMapping {
+		t.Fatalf("sync: %v", err)
+	}
+	if err := fd.Truncate(0); err != ErrPrivateMapping {
+		t.Fatalf("truncate: %v", err)
+	}
+
+	content, err := ioutil.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(content) != "hello world" {
+		t.Fatalf("the file is modified: %s", content)
+	}
+}
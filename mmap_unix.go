@@ -4,6 +4,7 @@ package ioengine
 
 import (
 	"os"
+	"unsafe"
 
 	"golang.org/x/sys/unix"
 )
@@ -14,7 +15,36 @@ func Mmap(fd *os.File, offset int64, length int, writable bool) ([]byte, error)
 	if writable {
 		prot |= unix.PROT_WRITE
 	}
-	return unix.Mmap(int(fd.Fd()), offset, length, prot, unix.MAP_SHARED)
+	return mmap(int(fd.Fd()), offset, length, prot, unix.MAP_SHARED)
+}
+
+// mmapFile maps the file from the offset according to the MemoryMap options.
+func mmapFile(fd *os.File, offset int64, length int, opt Options) ([]byte, error) {
+	prot := unix.PROT_READ
+	if opt.MmapWritable || opt.MmapPrivate {
+		prot |= unix.PROT_WRITE
+	}
+	flags := unix.MAP_SHARED
+	if opt.MmapPrivate {
+		flags = unix.MAP_PRIVATE
+	}
+	if opt.MmapPopulate {
+		flags |= mapPopulate
+	}
+	return mmap(int(fd.Fd()), offset, length, prot, flags)
+}
+
+// mapped turns the mapped memory address into a []byte.
+func mapped(addr uintptr, length int) []byte {
+	// Slice memory layout
+	var sl = struct {
+		addr uintptr
+		len  int
+		cap  int
+	}{addr, length, length}
+
+	// Use unsafe to turn sl into a []byte.
+	return *(*[]byte)(unsafe.Pointer(&sl))
 }
 
 // Madvise advises the kernel about how to handle the mapped slice.
@@ -22,6 +52,20 @@ func Madvise(b []byte) error {
 	return unix.Madvise(b, unix.MADV_NORMAL)
 }
 
+// MadviseWith advises the kernel to handle the mapped slice with the given access pattern.
+// It returns ErrNotSupported if the advice isn't supported by the OS.
+func MadviseWith(b []byte, advice MadviseMode) error {
+	flag, ok := madviseFlags[advice]
+	if !ok {
+		return ErrNotSupported
+	}
+	err := unix.Madvise(b, flag)
+	if err == unix.EINVAL && advice >= MadvHugePage {
+		return ErrNotSupported
+	}
+	return err
+}
+
 // Lock locks the maped slice, preventing it from being swapped out.
 func Lock(b []byte) error {
 	return unix.Mlock(b)
@@ -37,9 +81,21 @@ func Sync(b []byte) error {
 	return unix.Msync(b, unix.MS_SYNC)
 }
 
+// SyncAsync schedules mmap slice's all changes to be written back and returns immediately.
+func SyncAsync(b []byte) error {
+	return unix.Msync(b, unix.MS_ASYNC)
+}
+
 // Munmap unmaps mapped slice, this will also flush any remaining changes.
 func Munmap(b []byte) error {
-	return unix.Munmap(b)
+	if len(b) == 0 {
+		return unix.EINVAL
+	}
+	_, _, errno := unix.Syscall(unix.SYS_MUNMAP, uintptr(unsafe.Pointer(&b[0])), uintptr(len(b)), 0)
+	if errno != 0 {
+		return os.NewSyscallError("MUNMAP", errno)
+	}
+	return nil
 }
 
 // WriteAtv like linux pwritev, write to the specifies offset and dose not change the file offset.
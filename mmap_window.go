@@ -0,0 +1,276 @@
+package ioengine
+
+import (
+	"container/list"
+	"errors"
+	"os"
+	"sync"
+)
+
+// defaultMmapMaxWindows default max number of mapped windows
+const defaultMmapMaxWindows = 64
+
+// window a fixed-size aligned mapping of the file.
+type window struct {
+	off  int64
+	data []byte
+
+	// refs the number of readers and borrowed slices pinning the window
+	refs int
+
+	// evicted the window is evicted while it's pinned,
+	// it will be unmapped when the last pin is released.
+	evicted bool
+}
+
+// mmapWindows maps the file by fixed-size aligned windows on demand,
+// the least recently used windows are unmapped to keep the number of
+// mapped windows within the budget, so that the files larger than
+// the address space can be accessed by mmap.
+type mmapWindows struct {
+	fd  *os.File
+	opt Options
+
+	// size the window size, it's a multiple of the page size
+	size int64
+
+	// max the max number of mapped windows
+	max int
+
+	// lru the mapped windows, the front is the most recently used
+	lru *list.List
+
+	// index the mapped windows indexed by window offset
+	index map[int64]*list.Element
+
+	// evicted the evicted windows which are still pinned
+	evicted []*window
+
+	mu sync.Mutex
+}
+
+func newMmapWindows(fd *os.File, opt Options) *mmapWindows {
+	pageSize := os.Getpagesize()
+	size := (opt.MmapWindowSize + pageSize - 1) &^ (pageSize - 1)
+
+	max := opt.MmapMaxWindows
+	if max <= 0 {
+		max = defaultMmapMaxWindows
+	}
+
+	return &mmapWindows{
+		fd:    fd,
+		opt:   opt,
+		size:  int64(size),
+		max:   max,
+		lru:   list.New(),
+		index: make(map[int64]*list.Element),
+	}
+}
+
+// acquire returns the window covering the offset and pins it,
+// the window is mapped if it isn't mapped yet.
+func (ws *mmapWindows) acquire(off int64) (*window, error) {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	if ws.lru == nil {
+		return nil, errors.New("mmap: closed")
+	}
+
+	wOff := off - off%ws.size
+	if e, ok := ws.index[wOff]; ok {
+		ws.lru.MoveToFront(e)
+		w := e.Value.(*window)
+		w.refs++
+		return w, nil
+	}
+
+	data, err := mmapFile(ws.fd, wOff, int(ws.size), ws.opt)
+	if err != nil {
+		return nil, err
+	}
+	if ws.opt.MmapAdvice != MadvNormal {
+		if err := MadviseWith(data, ws.opt.MmapAdvice); err != nil {
+			Munmap(data)
+			return nil, err
+		}
+	}
+
+	w := &window{off: wOff, data: data, refs: 1}
+	ws.index[wOff] = ws.lru.PushFront(w)
+	ws.evict()
+
+	return w, nil
+}
+
+// release unpins the window, the evicted window is unmapped by the last release.
+func (ws *mmapWindows) release(w *window) error {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	return ws.unpin(w)
+}
+
+// unpin the caller must hold the lock.
+func (ws *mmapWindows) unpin(w *window) error {
+	w.refs--
+	if w.refs > 0 || !w.evicted {
+		return nil
+	}
+
+	for i, ew := range ws.evicted {
+		if ew == w {
+			ws.evicted = append(ws.evicted[:i], ws.evicted[i+1:]...)
+			break
+		}
+	}
+	return Munmap(w.data)
+}
+
+// evict unmaps the least recently used windows beyond the budget.
+// the caller must hold the lock.
+func (ws *mmapWindows) evict() {
+	for ws.lru.Len() > ws.max {
+		ws.remove(ws.lru.Back())
+	}
+}
+
+// remove removes the window from lru, the pinned window is unmapped later.
+// the caller must hold the lock.
+func (ws *mmapWindows) remove(e *list.Element) error {
+	w := ws.lru.Remove(e).(*window)
+	delete(ws.index, w.off)
+
+	if w.refs > 0 {
+		w.evicted = true
+		ws.evicted = append(ws.evicted, w)
+		return nil
+	}
+	return Munmap(w.data)
+}
+
+// copyAt copies between b and the file range [off, off+len(b)) across windows,
+// if write is true, b is copied into the windows, otherwise the windows are copied into b.
+// the range must be within the file size.
+func (ws *mmapWindows) copyAt(b []byte, off int64, write bool) (int, error) {
+	n := 0
+	for n < len(b) {
+		nc, err := ws.copyWindow(b[n:], off+int64(n), write)
+		n += nc
+		if err != nil {
+			return n, err
+		}
+	}
+
+	return n, nil
+}
+
+// copyWindow copies between b and the window covering the offset,
+// the window is released even if the copy faults.
+func (ws *mmapWindows) copyWindow(b []byte, off int64, write bool) (int, error) {
+	w, err := ws.acquire(off)
+	if err != nil {
+		return 0, err
+	}
+	defer ws.release(w)
+
+	if write {
+		return copy(w.data[off-w.off:], b), nil
+	}
+	return copy(b, w.data[off-w.off:]), nil
+}
+
+// slice returns the bytes of the range [off, off+n) pinned in a window.
+func (ws *mmapWindows) slice(off int64, n int) ([]byte, error) {
+	if off/ws.size != (off+int64(n)-1)/ws.size {
+		return nil, errors.New("mmap: slice crosses window boundary")
+	}
+
+	w, err := ws.acquire(off)
+	if err != nil {
+		return nil, err
+	}
+
+	start := off - w.off
+	end := start + int64(n)
+	return w.data[start:end:end], nil
+}
+
+// releaseSlice unpins the window which the slice is borrowed from.
+func (ws *mmapWindows) releaseSlice(b []byte) error {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	if ws.lru != nil {
+		for e := ws.lru.Front(); e != nil; e = e.Next() {
+			if w := e.Value.(*window); contains(w.data, b) {
+				return ws.unpin(w)
+			}
+		}
+	}
+	for _, w := range ws.evicted {
+		if contains(w.data, b) {
+			return ws.unpin(w)
+		}
+	}
+
+	return errors.New("mmap: slice isn't borrowed")
+}
+
+// sync flushes the mapped windows within the file range [start, end),
+// the pages of the unmapped windows are flushed by fsync.
+func (ws *mmapWindows) sync(start, end int64, async bool) error {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	if ws.lru == nil {
+		return errors.New("mmap: closed")
+	}
+
+	for e := ws.lru.Front(); e != nil; e = e.Next() {
+		w := e.Value.(*window)
+		wStart := max64(start, w.off) - w.off
+		wEnd := min64(end, w.off+int64(len(w.data))) - w.off
+		if wStart >= wEnd {
+			continue
+		}
+
+		var err error
+		if async {
+			err = SyncAsync(w.data[wStart:wEnd])
+		} else {
+			err = Sync(w.data[wStart:wEnd])
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// closed returns whether the windows are closed.
+func (ws *mmapWindows) closed() bool {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	return ws.lru == nil
+}
+
+// close unmaps all windows, the pinned windows are unmapped by the last release.
+func (ws *mmapWindows) close() (err error) {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	if ws.lru == nil {
+		return nil
+	}
+	for ws.lru.Len() > 0 {
+		if e := ws.remove(ws.lru.Front()); e != nil {
+			err = e
+		}
+	}
+	ws.lru = nil
+
+	return err
+}
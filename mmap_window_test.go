@@ -0,0 +1,117 @@
+package ioengine
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"testing"
+)
+
+func NewWindowedMemoryMap(writable bool) (*MemoryMap, error) {
+	opt := DefaultOptions
+	opt.IOEngine = MMap
+	opt.MmapWritable = writable
+	opt.MmapWindowSize = os.Getpagesize()
+	opt.MmapMaxWindows = 2
+	mmapID++
+	name := fmt.Sprintf("/tmp/mmap/%d", mmapID)
+	os.Remove(name)
+	return newMemoryMap(name, opt)
+}
+
+func TestMmapWindowReadAt(t *testing.T) {
+	fd, err := NewWindowedMemoryMap(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fd.Close()
+
+	pageSize := os.Getpagesize()
+	data := make([]byte, 8*pageSize)
+	for i := range data {
+		data[i] = byte(i / pageSize)
+	}
+	if _, err := fd.WriteAt(data, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	// read across the window boundaries
+	b := make([]byte, 3*pageSize)
+	nr, err := fd.ReadAt(b, int64(pageSize/2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nr != len(b) || !bytes.Equal(b, data[pageSize/2:pageSize/2+len(b)]) {
+		t.Fatal("readAt: unmatched content")
+	}
+
+	// the pinned window survives the eviction
+	s, err := fd.Slice(int64(7*pageSize), 16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fd.Slice(int64(pageSize-1), 2); err == nil {
+		t.Fatal("slice across windows should fail")
+	}
+	for off := 0; off < len(data); off += pageSize {
+		if _, err := fd.ReadAt(b[:1], int64(off)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !bytes.Equal(s, data[7*pageSize:7*pageSize+16]) {
+		t.Fatal("slice: unmatched content after eviction")
+	}
+	if err := fd.Release(s); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := fd.Seek(int64(6*pageSize), io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	c, err := fd.ReadByte()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c != 6 {
+		t.Fatal("readByte: unmatched content")
+	}
+
+	var buf bytes.Buffer
+	nw, err := fd.WriteTo(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if nw != int64(2*pageSize-1) || !bytes.Equal(buf.Bytes(), data[6*pageSize+1:]) {
+		t.Fatal("writeTo: unmatched content")
+	}
+
+	if _, err := fd.ReadAt(b, int64(len(data)-1)); err != io.EOF {
+		t.Fatal("read beyond file size should return EOF")
+	}
+}
+
+func TestMmapWindowWritable(t *testing.T) {
+	fd, err := NewWindowedMemoryMap(true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fd.Close()
+
+	pageSize := os.Getpagesize()
+	data := bytes.Repeat([]byte("window"), pageSize)
+	if _, err := fd.WriteAt(data, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := fd.Sync(); err != nil {
+		t.Fatal(err)
+	}
+
+	b := make([]byte, len(data))
+	if _, err := fd.File.ReadAt(b, 1); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b, data) {
+		t.Fatal("unmatched content")
+	}
+}
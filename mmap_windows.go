@@ -59,11 +59,35 @@ func Mmap(fd *os.File, offset int64, length int, writable bool) ([]byte, error)
 	return data, nil
 }
 
+// mmapFile maps the file from the offset according to the MemoryMap options.
+// the private copy-on-write mapping isn't supported yet.
+func mmapFile(fd *os.File, offset int64, length int, opt Options) ([]byte, error) {
+	if opt.MmapPrivate {
+		return nil, ErrNotSupported
+	}
+	return Mmap(fd, offset, length, opt.MmapWritable)
+}
+
+// remap resizes the mapping of fd, the file is mapped again and the old mapping is unmapped.
+func remap(fd *os.File, b []byte, length int, opt Options) ([]byte, error) {
+	data, err := mmapFile(fd, 0, length, opt)
+	if err != nil {
+		return nil, err
+	}
+	Munmap(b)
+	return data, nil
+}
+
 // Madvise do nothing on windows.
 func Madvise(b []byte) error {
 	return nil
 }
 
+// MadviseWith do nothing on windows.
+func MadviseWith(b []byte, advice MadviseMode) error {
+	return nil
+}
+
 // Lock locks the maped slice, preventing it from being swapped out.
 func Lock(b []byte) error {
 	err := windows.VirtualLock(uintptr(unsafe.Pointer(&b[0])), len(b))
@@ -83,6 +107,11 @@ func Sync(b []byte) error {
 	return os.NewSyscallError("FlushViewOfFile", err)
 }
 
+// SyncAsync FlushViewOfFile doesn't wait for the changes to be written to disk.
+func SyncAsync(b []byte) error {
+	return Sync(b)
+}
+
 // Munmap unmaps mapped slice, this will also flush any remaining changes.
 func Munmap(b []byte) error {
 	if err := Sync(b); err != nil {
@@ -0,0 +1,18 @@
+// +build linux,386
+
+package ioengine
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// readahead the 64-bit offset is split into the low and high words.
+func readahead(fd *os.File, off, n int64) error {
+	_, _, errno := unix.Syscall6(unix.SYS_READAHEAD, fd.Fd(), uintptr(off), uintptr(off>>32), uintptr(n), 0, 0)
+	if errno != 0 {
+		return os.NewSyscallError("READAHEAD", errno)
+	}
+	return nil
+}
@@ -0,0 +1,18 @@
+// +build linux,!386,!arm,!mips,!mipsle
+
+package ioengine
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// readahead the 64-bit offset is passed by one register.
+func readahead(fd *os.File, off, n int64) error {
+	_, _, errno := unix.Syscall(unix.SYS_READAHEAD, fd.Fd(), uintptr(off), uintptr(n))
+	if errno != 0 {
+		return os.NewSyscallError("READAHEAD", errno)
+	}
+	return nil
+}
@@ -0,0 +1,19 @@
+// +build linux,arm linux,mipsle
+
+package ioengine
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// readahead the 64-bit offset is passed by an even register pair,
+// so there is a padding argument after fd, the low word goes first.
+func readahead(fd *os.File, off, n int64) error {
+	_, _, errno := unix.Syscall6(unix.SYS_READAHEAD, fd.Fd(), 0, uintptr(off), uintptr(off>>32), uintptr(n), 0)
+	if errno != 0 {
+		return os.NewSyscallError("READAHEAD", errno)
+	}
+	return nil
+}
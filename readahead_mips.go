@@ -0,0 +1,19 @@
+// +build linux,mips
+
+package ioengine
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// readahead the 64-bit offset is passed by an even register pair,
+// so there is a padding argument after fd, the high word goes first on big endian.
+func readahead(fd *os.File, off, n int64) error {
+	_, _, errno := unix.Syscall6(unix.SYS_READAHEAD, fd.Fd(), 0, uintptr(off>>32), uintptr(off), uintptr(n), 0)
+	if errno != 0 {
+		return os.NewSyscallError("READAHEAD", errno)
+	}
+	return nil
+}
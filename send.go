@@ -0,0 +1,93 @@
+package ioengine
+
+import (
+	"errors"
+	"io"
+	"math"
+	"os"
+)
+
+// sender is impled by the engines which send the file range to a writer, eg: FileIO and MemoryMap.
+type sender interface {
+	SendTo(w io.Writer, off, n int64) (int64, error)
+}
+
+// sendCopy copies n bytes of r from off to w, it's the fallback of sendFile.
+func sendCopy(w io.Writer, r io.ReaderAt, off, n int64) (int64, error) {
+	return io.Copy(w, io.NewSectionReader(r, off, n))
+}
+
+// send sends n bytes of fd from off to w in the kernel if possible.
+func send(w io.Writer, fd *os.File, off, n int64) (int64, error) {
+	if off < 0 || n < 0 {
+		return 0, errors.New("send: invalid range")
+	}
+	sent, err := sendFile(w, fd, off, n)
+	if err != ErrNotSupported {
+		return sent, err
+	}
+	return sendCopy(w, fd, off, n)
+}
+
+// WriteTo writes the file from the current offset to the end of file to w,
+// by sendfile or splice if w is a socket, file or pipe.
+// It implements the io.WriterTo interface.
+func (fi *FileIO) WriteTo(w io.Writer) (int64, error) {
+	off, err := fi.File.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, err
+	}
+	n, err := fi.SendTo(w, off, math.MaxInt64-off)
+	if _, serr := fi.File.Seek(off+n, io.SeekStart); err == nil {
+		err = serr
+	}
+	return n, err
+}
+
+// SendTo writes n bytes of the file from off to w, it stops at the end of file.
+// if w is a *net.TCPConn, *net.UnixConn, *os.File or pipe, the data is sent
+// by sendfile or splice without passing through the user space,
+// otherwise it falls back to the copy loop. The file offset isn't changed.
+func (fi *FileIO) SendTo(w io.Writer, off, n int64) (int64, error) {
+	return send(w, fi.File, off, n)
+}
+
+// WriteTo writes the file from the current offset to the end of file to w.
+// It implements the io.WriterTo interface.
+func (dio *DirectIO) WriteTo(w io.Writer) (int64, error) {
+	off, err := dio.File.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, err
+	}
+	n, err := dio.SendTo(w, off, math.MaxInt64-off)
+	if _, serr := dio.File.Seek(off+n, io.SeekStart); err == nil {
+		err = serr
+	}
+	return n, err
+}
+
+// SendTo writes n bytes of the file from off to w, it stops at the end of file.
+// the unaligned sendfile can't be done by the DIO fd, the data is sent
+// from the page cache by a buffered fd of the same file. The file offset isn't changed.
+func (dio *DirectIO) SendTo(w io.Writer, off, n int64) (int64, error) {
+	fd, err := dio.bufferedFile()
+	if err != nil {
+		return 0, err
+	}
+	return send(w, fd, off, n)
+}
+
+// bufferedFile returns the buffered fd of the file, it's opened on demand.
+func (dio *DirectIO) bufferedFile() (*os.File, error) {
+	dio.bufferedMu.Lock()
+	defer dio.bufferedMu.Unlock()
+
+	if dio.buffered == nil {
+		fd, err := reopenBuffered(dio.File, dio.path)
+		if err != nil {
+			return nil, err
+		}
+		dio.buffered = fd
+	}
+	return dio.buffered, nil
+}
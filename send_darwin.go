@@ -0,0 +1,23 @@
+// +build darwin
+
+package ioengine
+
+import (
+	"io"
+	"os"
+)
+
+// sendFile isn't impled on darwin yet, the copy loop is used.
+func sendFile(w io.Writer, fd *os.File, off, n int64) (int64, error) {
+	return 0, ErrNotSupported
+}
+
+// writeMapped writes the mapped bytes to w.
+func writeMapped(w io.Writer, b []byte) (int, error) {
+	return w.Write(b)
+}
+
+// reopenBuffered opens the same file by name.
+func reopenBuffered(fd *os.File, name string) (*os.File, error) {
+	return os.Open(name)
+}
@@ -0,0 +1,152 @@
+// +build linux
+
+package ioengine
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"syscall"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// maxSendChunk the max bytes sent by once call of sendfile, splice or vmsplice.
+const maxSendChunk = 1 << 30
+
+// isPipe returns whether w is a pipe.
+func isPipe(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	stat, err := f.Stat()
+	return err == nil && stat.Mode()&os.ModeNamedPipe != 0
+}
+
+// sendFile sends n bytes of fd from off to w in the kernel, by splice if w is a pipe,
+// otherwise by sendfile, eg: to *net.TCPConn, *net.UnixConn or *os.File.
+// It returns ErrNotSupported if nothing is sent and w isn't supported,
+// the caller should fall back to the copy loop.
+func sendFile(w io.Writer, fd *os.File, off, n int64) (int64, error) {
+	sc, ok := w.(syscall.Conn)
+	if !ok {
+		return 0, ErrNotSupported
+	}
+	rc, err := sc.SyscallConn()
+	if err != nil {
+		return 0, ErrNotSupported
+	}
+	pipe := isPipe(w)
+
+	var sent int64
+	var serr error
+	werr := rc.Write(func(wfd uintptr) bool {
+		for sent < n {
+			size := n - sent
+			if size > maxSendChunk {
+				size = maxSendChunk
+			}
+
+			roff := off + sent
+			// the count of Splice is int64 on 64-bit platforms and int on the others
+			var nc int
+			if pipe {
+				ns, err := unix.Splice(int(fd.Fd()), &roff, int(wfd), nil, int(size), unix.SPLICE_F_MOVE|unix.SPLICE_F_NONBLOCK)
+				nc, serr = int(ns), err
+			} else {
+				nc, serr = unix.Sendfile(int(wfd), int(fd.Fd()), &roff, int(size))
+			}
+			nw := int64(nc)
+			if nw > 0 {
+				sent += nw
+			}
+
+			switch serr {
+			case nil:
+				if nw == 0 {
+					// the end of file
+					return true
+				}
+			case unix.EINTR:
+			case unix.EAGAIN:
+				// wait for w to be writable
+				serr = nil
+				return false
+			default:
+				return true
+			}
+		}
+		return true
+	})
+	if werr != nil {
+		return sent, werr
+	}
+	if serr != nil {
+		if sent == 0 && (serr == unix.EINVAL || serr == unix.ENOSYS || serr == unix.EOPNOTSUPP) {
+			return 0, ErrNotSupported
+		}
+		if pipe {
+			return sent, os.NewSyscallError("SPLICE", serr)
+		}
+		return sent, os.NewSyscallError("SENDFILE", serr)
+	}
+
+	return sent, nil
+}
+
+// writeMapped writes the mapped bytes to w, by vmsplice if w is a pipe,
+// so that the pages are moved into the pipe without copy, otherwise by Write.
+// the pages spliced into the pipe reflect the later changes until they are read.
+func writeMapped(w io.Writer, b []byte) (int, error) {
+	if !isPipe(w) {
+		return w.Write(b)
+	}
+	rc, err := w.(*os.File).SyscallConn()
+	if err != nil {
+		return w.Write(b)
+	}
+
+	var nw int
+	var serr error
+	werr := rc.Write(func(wfd uintptr) bool {
+		for nw < len(b) {
+			size := len(b) - nw
+			if size > maxSendChunk {
+				size = maxSendChunk
+			}
+			iov := unix.Iovec{Base: (*byte)(unsafe.Pointer(&b[nw]))}
+			iov.SetLen(size)
+
+			var nc int
+			nc, serr = unix.Vmsplice(int(wfd), []unix.Iovec{iov}, unix.SPLICE_F_NONBLOCK)
+			if nc > 0 {
+				nw += nc
+			}
+			switch serr {
+			case nil:
+			case unix.EINTR:
+			case unix.EAGAIN:
+				serr = nil
+				return false
+			default:
+				return true
+			}
+		}
+		return true
+	})
+	if werr != nil {
+		return nw, werr
+	}
+	if serr != nil {
+		return nw, os.NewSyscallError("VMSPLICE", serr)
+	}
+	return nw, nil
+}
+
+// reopenBuffered opens the same file as fd without O_DIRECT by /proc,
+// so that it works even if the file is renamed or unlinked.
+func reopenBuffered(fd *os.File, name string) (*os.File, error) {
+	return os.Open(fmt.Sprintf("/proc/self/fd/%d", fd.Fd()))
+}
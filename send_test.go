@@ -0,0 +1,123 @@
+package ioengine
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"testing"
+)
+
+// receive sends the range by send over a TCP connection and returns the received bytes.
+func receive(t *testing.T, send func(w io.Writer) (int64, error)) []byte {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	received := make(chan []byte, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			received <- nil
+			return
+		}
+		defer conn.Close()
+		b, _ := ioutil.ReadAll(conn)
+		received <- b
+	}()
+
+	conn, err := net.Dial("tcp", ln.Addr().String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := send(conn); err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+
+	return <-received
+}
+
+func TestSendTo(t *testing.T) {
+	fd, err := NewFileIO()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fd.Close()
+
+	content := bytes.Repeat([]byte("0123456789"), 3*BlockSize)
+	if err := fd.Truncate(0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fd.WriteAt(content, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	b := receive(t, func(w io.Writer) (int64, error) {
+		return fd.SendTo(w, 10, 1000)
+	})
+	if !bytes.Equal(b, content[10:1010]) {
+		t.Fatal("sendfile: unmatched content")
+	}
+
+	// WriteTo sends from the file offset and advances it
+	if _, err := fd.Seek(100, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if n, err := fd.WriteTo(&buf); err != nil || n != int64(len(content)-100) {
+		t.Fatalf("write to: %d, %v", n, err)
+	}
+	if !bytes.Equal(buf.Bytes(), content[100:]) {
+		t.Fatal("write to: unmatched content")
+	}
+	if off, err := fd.Seek(0, io.SeekCurrent); err != nil || off != int64(len(content)) {
+		t.Fatalf("write to: unmatched offset %d", off)
+	}
+
+	dio, err := NewDirectIO()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dio.Close()
+	if _, err := CopyRange(dio, 0, fd, 0, int64(len(content))); err != nil {
+		t.Fatal(err)
+	}
+	b = receive(t, func(w io.Writer) (int64, error) {
+		return dio.SendTo(w, 1, 5000)
+	})
+	if !bytes.Equal(b, content[1:5001]) {
+		t.Fatal("dio sendfile: unmatched content")
+	}
+
+	mmap, err := NewWritableMemoryMap()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mmap.Close()
+	if _, err := mmap.WriteAt(content, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	// vmsplice the mapping into a pipe
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	done := make(chan []byte, 1)
+	go func() {
+		b, _ := ioutil.ReadAll(r)
+		done <- b
+	}()
+	if n, err := mmap.SendTo(w, 0, int64(len(content))); err != nil || n != int64(len(content)) {
+		t.Fatalf("mmap send to pipe: %d, %v", n, err)
+	}
+	w.Close()
+	if !bytes.Equal(<-done, content) {
+		t.Fatal("vmsplice: unmatched content")
+	}
+}
@@ -0,0 +1,172 @@
+package ioengine
+
+import (
+	"io"
+	"os"
+	"syscall"
+)
+
+// sparseCopyChunk the max bytes copied by once call of ReadAt and WriteAt in CopySparse.
+const sparseCopyChunk = 256 * BlockSize
+
+// DataExtent the file range [Off, Off+Len) which contains data.
+type DataExtent struct {
+	Off int64
+	Len int64
+}
+
+// DataIterator iterates over the data extents of a sparse file by SeekData and SeekHole.
+// the file system without hole support reports the whole file as one data extent.
+// like NextData, f must not be used by other IO or Seek during Next.
+type DataIterator struct {
+	f   File
+	off int64
+	cur DataExtent
+	err error
+}
+
+// NextData returns the offset of the next data at or after off,
+// it returns io.EOF if there is no data after off.
+// the Seek offset of f is moved and restored before it returns, FileIO and DirectIO
+// share it with Read and Write, so f must not be used by other IO or Seek meanwhile.
+func NextData(f File, off int64) (int64, error) {
+	return seekSparse(f, off, SeekData)
+}
+
+// NextHole returns the offset of the next hole at or after off,
+// the end of file is an implicit hole, it returns io.EOF if off is beyond the end of file.
+// like NextData, f must not be used by other IO or Seek meanwhile.
+func NextHole(f File, off int64) (int64, error) {
+	return seekSparse(f, off, SeekHole)
+}
+
+// seekSparse seeks to the next data or hole, then restores the Seek offset,
+// the error of the restore is returned unless the seek fails.
+func seekSparse(f File, off int64, whence int) (int64, error) {
+	cur, err := f.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := f.Seek(off, whence)
+	if isNoData(err) {
+		err = io.EOF
+	}
+	if _, serr := f.Seek(cur, io.SeekStart); serr != nil && (err == nil || err == io.EOF) {
+		return 0, serr
+	}
+	if err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// isNoData returns whether the Seek error is ENXIO, no more data or hole after the offset.
+func isNoData(err error) bool {
+	switch e := err.(type) {
+	case *os.PathError:
+		err = e.Err
+	case *os.SyscallError:
+		err = e.Err
+	}
+	return err == syscall.ENXIO
+}
+
+// DataExtents returns the iterator over the data extents of f from the beginning.
+func DataExtents(f File) *DataIterator {
+	return &DataIterator{f: f}
+}
+
+// Next advances to the next data extent, it returns false at the end of file or on error.
+func (it *DataIterator) Next() bool {
+	if it.err != nil {
+		return false
+	}
+
+	start, err := NextData(it.f, it.off)
+	if err != nil {
+		if err != io.EOF {
+			it.err = err
+		}
+		return false
+	}
+	end, err := NextHole(it.f, start)
+	if err != nil {
+		it.err = err
+		return false
+	}
+
+	it.cur = DataExtent{Off: start, Len: end - start}
+	it.off = end
+	return true
+}
+
+// Extent returns the current data extent.
+func (it *DataIterator) Extent() DataExtent {
+	return it.cur
+}
+
+// Err returns the error which stops the iteration, if any.
+func (it *DataIterator) Err() error {
+	return it.err
+}
+
+// CopySparse copies src to dst and preserves the holes of src,
+// only the data extents are read and written, dst is truncated to the size of src.
+// like NextData, src must not be used by other IO or Seek meanwhile.
+// the extents are extended to the BlockSize boundaries with an aligned buffer,
+// so that the Files opened by DIO or AIO are supported.
+// It returns the number of bytes copied, excluding the holes.
+func CopySparse(dst, src File) (int64, error) {
+	stat, err := src.Stat()
+	if err != nil {
+		return 0, err
+	}
+	if err := dst.Truncate(0); err != nil {
+		return 0, err
+	}
+
+	buf, err := MemAlign(sparseCopyChunk)
+	if err != nil {
+		return 0, err
+	}
+
+	var copied int64
+	it := DataExtents(src)
+	for it.Next() {
+		e := it.Extent()
+		off := e.Off &^ (BlockSize - 1)
+		end := (e.Off + e.Len + BlockSize - 1) &^ (BlockSize - 1)
+
+		for off < end {
+			n := end - off
+			if n > sparseCopyChunk {
+				n = sparseCopyChunk
+			}
+
+			nr, err := src.ReadAt(buf[:n], off)
+			if err != nil && err != io.EOF {
+				return copied, err
+			}
+			if nr == 0 {
+				break
+			}
+			// the tail of file is padded to the block boundary, it's truncated at last
+			nw := (int64(nr) + BlockSize - 1) &^ (BlockSize - 1)
+			for i := nr; i < int(nw); i++ {
+				buf[i] = 0
+			}
+			if _, err := dst.WriteAt(buf[:nw], off); err != nil {
+				return copied, err
+			}
+
+			copied += int64(nr)
+			off += nw
+		}
+	}
+	if err := it.Err(); err != nil {
+		return copied, err
+	}
+
+	return copied, dst.Truncate(stat.Size())
+}
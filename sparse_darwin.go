@@ -0,0 +1,10 @@
+// +build darwin
+
+package ioengine
+
+const (
+	// SeekData the whence of Seek, seeks to the next data at or after the offset
+	SeekData = 4
+	// SeekHole the whence of Seek, seeks to the next hole at or after the offset
+	SeekHole = 3
+)
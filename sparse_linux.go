@@ -0,0 +1,11 @@
+// +build linux
+
+package ioengine
+
+// SEEK_DATA and SEEK_HOLE are added on linux v3.1
+const (
+	// SeekData the whence of Seek, seeks to the next data at or after the offset
+	SeekData = 3
+	// SeekHole the whence of Seek, seeks to the next hole at or after the offset
+	SeekHole = 4
+)
@@ -0,0 +1,118 @@
+// +build linux
+
+package ioengine
+
+import (
+	"bytes"
+	"io"
+	"syscall"
+	"testing"
+)
+
+func TestSparse(t *testing.T) {
+	src, err := NewFileIO()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+
+	size := int64(4 << 20)
+	data := bytes.Repeat([]byte("sparse"), 1000)
+	if err := src.Truncate(0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := src.WriteAt(data, 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := src.WriteAt(data, 1<<20); err != nil {
+		t.Fatal(err)
+	}
+	if err := src.Truncate(size); err != nil {
+		t.Fatal(err)
+	}
+
+	off, err := NextHole(src, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if off == size {
+		t.Skip("the file system doesn't support holes")
+	}
+	if off, err := NextData(src, int64(len(data))+BlockSize); err != nil || off != 1<<20 {
+		t.Fatalf("next data: %d, %v", off, err)
+	}
+	if _, err := NextData(src, 2<<20); err != io.EOF {
+		t.Fatalf("next data after the last extent: %v", err)
+	}
+
+	var extents []DataExtent
+	it := DataExtents(src)
+	for it.Next() {
+		extents = append(extents, it.Extent())
+	}
+	if it.Err() != nil {
+		t.Fatal(it.Err())
+	}
+	if len(extents) != 2 || extents[0].Off != 0 || extents[1].Off != 1<<20 {
+		t.Fatalf("unmatched extents: %v", extents)
+	}
+
+	dst, err := NewDirectIO()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dst.Close()
+
+	if _, err := CopySparse(dst, src); err != nil {
+		t.Fatal(err)
+	}
+	stat, err := dst.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stat.Size() != size {
+		t.Fatalf("unmatched size: %d", stat.Size())
+	}
+	if blocks := stat.Sys().(*syscall.Stat_t).Blocks * 512; blocks >= size {
+		t.Fatalf("the holes aren't preserved: %d bytes allocated", blocks)
+	}
+
+	b, err := MemAlign(uint(len(data)+BlockSize-1) &^ (BlockSize - 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dst.ReadAt(b, 1<<20); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(b[:len(data)], data) {
+		t.Fatal("unmatched content")
+	}
+
+	// the engines with own offset support SeekData
+	mmap, err := NewMemoryMap()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mmap.Close()
+	if _, err := CopySparse(mmap, src); err != nil {
+		t.Fatal(err)
+	}
+	if off, err := mmap.Seek(BlockSize*2, SeekData); err != nil || off != 1<<20 {
+		t.Fatalf("mmap seek data: %d, %v", off, err)
+	}
+}
+
+func TestAsyncIOSeekWhence(t *testing.T) {
+	fd, err := NewAsyncIO()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fd.Close()
+
+	if _, err := fd.Seek(0, 5); err == nil {
+		t.Fatal("seek with invalid whence should fail")
+	}
+	if _, err := NextHole(fd, 0); err != nil && err != io.EOF {
+		t.Fatal(err)
+	}
+}
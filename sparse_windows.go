@@ -0,0 +1,12 @@
+// +build windows
+
+package ioengine
+
+// SeekData and SeekHole aren't supported by windows Seek,
+// they are defined to keep the API consistent.
+const (
+	// SeekData the whence of Seek, seeks to the next data at or after the offset
+	SeekData = 3
+	// SeekHole the whence of Seek, seeks to the next hole at or after the offset
+	SeekHole = 4
+)
@@ -0,0 +1,94 @@
+package ioengine
+
+import "time"
+
+// StatxMask the optional fields of Statx, they are the same as linux STATX_*.
+type StatxMask uint32
+
+const (
+	// StatxBtime Btime is filled
+	StatxBtime StatxMask = 0x800
+	// StatxMntID MntID is filled, linux 5.8 or later
+	StatxMntID StatxMask = 0x1000
+	// StatxDioAlign DioMemAlign and DioOffsetAlign are filled, linux 6.1 or later
+	StatxDioAlign StatxMask = 0x2000
+)
+
+// StatxAttr the file attributes, they are the same as linux STATX_ATTR_*.
+type StatxAttr uint64
+
+const (
+	// StatxAttrCompressed the file is compressed by the file system
+	StatxAttrCompressed StatxAttr = 0x4
+	// StatxAttrImmutable the file can't be modified, deleted or renamed
+	StatxAttrImmutable StatxAttr = 0x10
+	// StatxAttrAppend the file can only be opened in append mode for writing
+	StatxAttrAppend StatxAttr = 0x20
+	// StatxAttrNodump the file isn't a candidate for backup by dump
+	StatxAttrNodump StatxAttr = 0x40
+	// StatxAttrEncrypted the file is encrypted by the file system
+	StatxAttrEncrypted StatxAttr = 0x800
+	// StatxAttrVerity the file is protected by fs-verity
+	StatxAttrVerity StatxAttr = 0x100000
+	// StatxAttrDax the file is in the DAX state, the page cache is bypassed
+	StatxAttrDax StatxAttr = 0x200000
+)
+
+// Statx the extended file status, it's filled by statx on linux and fstat on the others.
+type Statx struct {
+	// Mask the optional fields which are filled
+	Mask StatxMask
+
+	// Mode the raw st_mode, including the file type
+	Mode  uint32
+	Nlink uint64
+	Uid   uint32
+	Gid   uint32
+	Ino   uint64
+	Size  int64
+
+	// Blocks the number of 512-byte blocks allocated, it's less than Size for the sparse files
+	Blocks int64
+
+	// BlockSize the preferred IO block size
+	BlockSize int64
+
+	// Attributes the file attributes, AttributesMask reports the ones supported by the file system
+	Attributes     StatxAttr
+	AttributesMask StatxAttr
+
+	Atime time.Time
+	Btime time.Time
+	Ctime time.Time
+	Mtime time.Time
+
+	// MntID the id of the mount containing the file
+	MntID uint64
+
+	// DioMemAlign the memory alignment required by the direct IO, 0 if it isn't supported
+	DioMemAlign uint32
+
+	// DioOffsetAlign the file offset alignment required by the direct IO, 0 if it isn't supported
+	DioOffsetAlign uint32
+}
+
+// AllocatedSize returns the bytes allocated on disk, eg: for the quota accounting.
+func (st *Statx) AllocatedSize() int64 {
+	return st.Blocks * 512
+}
+
+// Statx returns the extended status of the file.
+func (fi *FileIO) Statx() (*Statx, error) {
+	return statx(fi.File)
+}
+
+// Statx returns the extended status of the file.
+func (dio *DirectIO) Statx() (*Statx, error) {
+	return statx(dio.File)
+}
+
+// Statx returns the extended status of the file,
+// the dirty pages of the mapping may not be allocated yet.
+func (mmap *MemoryMap) Statx() (*Statx, error) {
+	return statx(mmap.File)
+}
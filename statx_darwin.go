@@ -0,0 +1,61 @@
+// +build darwin
+
+package ioengine
+
+import (
+	"os"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// the file flags of darwin chflags
+const (
+	ufNodump     = 0x1
+	ufImmutable  = 0x2
+	ufAppend     = 0x4
+	ufCompressed = 0x20
+	sfImmutable  = 0x20000
+	sfAppend     = 0x40000
+)
+
+// statx darwin has no statx, it's filled by fstat, the birth time is always filled,
+// the attributes are translated from the file flags.
+func statx(fd *os.File) (*Statx, error) {
+	var st unix.Stat_t
+	if err := unix.Fstat(int(fd.Fd()), &st); err != nil {
+		return nil, os.NewSyscallError("FSTAT", err)
+	}
+
+	var attrs StatxAttr
+	if st.Flags&(ufImmutable|sfImmutable) != 0 {
+		attrs |= StatxAttrImmutable
+	}
+	if st.Flags&(ufAppend|sfAppend) != 0 {
+		attrs |= StatxAttrAppend
+	}
+	if st.Flags&ufCompressed != 0 {
+		attrs |= StatxAttrCompressed
+	}
+	if st.Flags&ufNodump != 0 {
+		attrs |= StatxAttrNodump
+	}
+
+	return &Statx{
+		Mask:           StatxBtime,
+		Mode:           uint32(st.Mode),
+		Nlink:          uint64(st.Nlink),
+		Uid:            st.Uid,
+		Gid:            st.Gid,
+		Ino:            st.Ino,
+		Size:           st.Size,
+		Blocks:         st.Blocks,
+		BlockSize:      int64(st.Blksize),
+		Attributes:     attrs,
+		AttributesMask: StatxAttrImmutable | StatxAttrAppend | StatxAttrCompressed | StatxAttrNodump,
+		Atime:          time.Unix(st.Atimespec.Unix()),
+		Btime:          time.Unix(st.Birthtimespec.Unix()),
+		Ctime:          time.Unix(st.Ctimespec.Unix()),
+		Mtime:          time.Unix(st.Mtimespec.Unix()),
+	}, nil
+}
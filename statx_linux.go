@@ -0,0 +1,79 @@
+// +build linux
+
+package ioengine
+
+import (
+	"os"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+// statxExt the fields of struct statx after stx_dev_minor,
+// they are in the padding of unix.Statx_t.
+type statxExt struct {
+	mntID          uint64
+	dioMemAlign    uint32
+	dioOffsetAlign uint32
+}
+
+// statx falls back to fstat if statx isn't supported, eg: linux before 4.11.
+func statx(fd *os.File) (*Statx, error) {
+	var st unix.Statx_t
+	mask := unix.STATX_BASIC_STATS | int(StatxBtime|StatxMntID|StatxDioAlign)
+	err := unix.Statx(int(fd.Fd()), "", unix.AT_EMPTY_PATH, mask, &st)
+	if err == unix.ENOSYS {
+		return fstat(fd)
+	}
+	if err != nil {
+		return nil, os.NewSyscallError("STATX", err)
+	}
+
+	ext := (*statxExt)(unsafe.Pointer(uintptr(unsafe.Pointer(&st)) + unsafe.Offsetof(st.Dev_minor) + unsafe.Sizeof(st.Dev_minor)))
+	return &Statx{
+		Mask:           StatxMask(st.Mask) & (StatxBtime | StatxMntID | StatxDioAlign),
+		Mode:           uint32(st.Mode),
+		Nlink:          uint64(st.Nlink),
+		Uid:            st.Uid,
+		Gid:            st.Gid,
+		Ino:            st.Ino,
+		Size:           int64(st.Size),
+		Blocks:         int64(st.Blocks),
+		BlockSize:      int64(st.Blksize),
+		Attributes:     StatxAttr(st.Attributes),
+		AttributesMask: StatxAttr(st.Attributes_mask),
+		Atime:          statxTime(st.Atime),
+		Btime:          statxTime(st.Btime),
+		Ctime:          statxTime(st.Ctime),
+		Mtime:          statxTime(st.Mtime),
+		MntID:          ext.mntID,
+		DioMemAlign:    ext.dioMemAlign,
+		DioOffsetAlign: ext.dioOffsetAlign,
+	}, nil
+}
+
+func statxTime(ts unix.StatxTimestamp) time.Time {
+	return time.Unix(ts.Sec, int64(ts.Nsec))
+}
+
+// fstat fills the basic fields only.
+func fstat(fd *os.File) (*Statx, error) {
+	var st unix.Stat_t
+	if err := unix.Fstat(int(fd.Fd()), &st); err != nil {
+		return nil, os.NewSyscallError("FSTAT", err)
+	}
+	return &Statx{
+		Mode:      uint32(st.Mode),
+		Nlink:     uint64(st.Nlink),
+		Uid:       st.Uid,
+		Gid:       st.Gid,
+		Ino:       st.Ino,
+		Size:      st.Size,
+		Blocks:    int64(st.Blocks),
+		BlockSize: int64(st.Blksize),
+		Atime:     time.Unix(st.Atim.Unix()),
+		Ctime:     time.Unix(st.Ctim.Unix()),
+		Mtime:     time.Unix(st.Mtim.Unix()),
+	}, nil
+}
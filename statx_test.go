@@ -0,0 +1,85 @@
+// +build linux
+
+package ioengine
+
+import (
+	"syscall"
+	"testing"
+)
+
+func testStatx(t *testing.T, fd File) {
+	b, err := MemAlign(BlockSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := fd.Truncate(256 * BlockSize); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fd.WriteAt(b, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := fd.Sync(); err != nil {
+		t.Fatal(err)
+	}
+
+	st, err := fd.Statx()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if st.Size != 256*BlockSize {
+		t.Fatalf("unmatched size %d", st.Size)
+	}
+	if st.AllocatedSize() < BlockSize || st.AllocatedSize() >= st.Size {
+		t.Fatalf("unmatched allocated size %d of the sparse file", st.AllocatedSize())
+	}
+
+	fi, err := fd.Stat()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sys, ok := fi.Sys().(*syscall.Stat_t); ok && sys.Ino != st.Ino {
+		t.Fatalf("unmatched inode %d != %d", st.Ino, sys.Ino)
+	}
+	if st.Mtime.IsZero() || (st.Mask&StatxBtime != 0 && st.Btime.After(st.Mtime)) {
+		t.Fatalf("unmatched times btime %v, mtime %v", st.Btime, st.Mtime)
+	}
+	if st.Mask&StatxMntID != 0 && st.MntID == 0 {
+		t.Fatal("the mount id should be filled")
+	}
+	if st.Mask&StatxDioAlign != 0 && st.DioOffsetAlign == 0 {
+		t.Fatal("the direct IO alignment should be filled")
+	}
+	if st.Attributes&StatxAttrImmutable != 0 {
+		t.Fatal("the file shouldn't be immutable")
+	}
+}
+
+func TestStatx(t *testing.T) {
+	fi, err := NewFileIO()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fi.Close()
+	testStatx(t, fi)
+
+	dio, err := NewDirectIO()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dio.Close()
+	testStatx(t, dio)
+
+	mmap, err := NewWritableMemoryMap()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer mmap.Close()
+	testStatx(t, mmap)
+
+	aio, err := NewAsyncIO()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer aio.Close()
+	testStatx(t, aio)
+}
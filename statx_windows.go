@@ -0,0 +1,10 @@
+// +build windows
+
+package ioengine
+
+import "os"
+
+// statx windows has no statx.
+func statx(fd *os.File) (*Statx, error) {
+	return nil, ErrNotSupported
+}
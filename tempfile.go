@@ -0,0 +1,129 @@
+package ioengine
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// tempNameAttempts the max attempts to pick an unused temporary name
+const tempNameAttempts = 10000
+
+// tempSeq makes the temporary names unique within the process, it's updated atomically.
+var tempSeq uint64
+
+// TempFile a temporary file which isn't visible until it's published.
+// on linux, it's an unnamed file created by O_TMPFILE, the file system reclaims it
+// once it's closed, even if the process crashes. where O_TMPFILE isn't supported,
+// it's created with a hidden temporary name in the directory and removed by Close
+// if it isn't published.
+type TempFile struct {
+	File
+
+	// name the path of the named temporary file, it's empty for O_TMPFILE
+	name string
+
+	published bool
+	mu        sync.Mutex
+}
+
+// CreateTemp creates a temporary file in dir through the IO engine of opt,
+// the file must be on the same file system as the name it's published to.
+// the file is always opened for reading and writing, O_CREATE, O_EXCL,
+// O_TRUNC and O_APPEND of opt.Flag are ignored, MemFD mode isn't supported.
+// opt.FileLock is ignored, the temporary file isn't shared until it's published.
+func CreateTemp(dir string, opt Options) (*TempFile, error) {
+	if opt.IOEngine == MemFD {
+		return nil, ErrNotSupported
+	}
+	opt.Flag = opt.Flag&^(os.O_RDONLY|os.O_WRONLY|os.O_RDWR|os.O_CREATE|os.O_EXCL|os.O_TRUNC|os.O_APPEND) | os.O_RDWR
+	opt.FileLock = None
+
+	name, fd, err := openTmpfile(dir, opt)
+	if err == ErrNotSupported {
+		return createNamedTemp(dir, opt)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := newFile(name, fd, opt)
+	if err != nil {
+		return nil, err
+	}
+	return &TempFile{File: withSyncPolicy(f, opt)}, nil
+}
+
+// createNamedTemp creates the temporary file with a hidden unused name in dir.
+func createNamedTemp(dir string, opt Options) (*TempFile, error) {
+	opt.Flag |= os.O_CREATE | os.O_EXCL
+
+	for i := 0; i < tempNameAttempts; i++ {
+		name := filepath.Join(dir, tempName())
+		f, err := Open(name, opt)
+		if os.IsExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return &TempFile{File: f, name: name}, nil
+	}
+	return nil, errors.New("tempfile: too many attempts to create the temporary file")
+}
+
+// tempName returns a hidden name which is unlikely to be used.
+func tempName() string {
+	seq := atomic.AddUint64(&tempSeq, 1)
+	return ".tmp-" + strconv.Itoa(os.Getpid()) + "-" +
+		strconv.FormatInt(time.Now().UnixNano(), 36) + "-" + strconv.FormatUint(seq, 36)
+}
+
+// Publish makes the file visible as name atomically, the existing name is replaced.
+// the file is still open after it's published. Publish doesn't sync the file,
+// call Sync first if the content must be durable once it's visible.
+func (tf *TempFile) Publish(name string) error {
+	tf.mu.Lock()
+	defer tf.mu.Unlock()
+
+	if tf.published {
+		return errors.New("tempfile: already published")
+	}
+
+	var err error
+	if tf.name == "" {
+		err = linkTmpfile(tf.Fd(), name)
+	} else {
+		err = os.Rename(tf.name, name)
+	}
+	if err != nil {
+		return err
+	}
+
+	tf.published = true
+	return nil
+}
+
+// Unwrap returns the wrapped File.
+func (tf *TempFile) Unwrap() File {
+	return tf.File
+}
+
+// Close closes the file, the named temporary file is removed if it isn't published.
+func (tf *TempFile) Close() error {
+	err := tf.File.Close()
+
+	tf.mu.Lock()
+	defer tf.mu.Unlock()
+
+	if tf.name != "" && !tf.published {
+		if rerr := os.Remove(tf.name); rerr != nil && !os.IsNotExist(rerr) && err == nil {
+			err = rerr
+		}
+	}
+	return err
+}
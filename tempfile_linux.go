@@ -0,0 +1,73 @@
+// +build linux
+
+package ioengine
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"syscall"
+
+	"golang.org/x/sys/unix"
+)
+
+// openTmpfile opens an unnamed file in dir by O_TMPFILE, the name is the fd path in /proc,
+// it returns ErrNotSupported if the kernel or the file system doesn't support O_TMPFILE.
+func openTmpfile(dir string, opt Options) (string, *os.File, error) {
+	flag := opt.Flag | unix.O_TMPFILE
+	if opt.IOEngine == DIO || opt.IOEngine == AIO {
+		flag |= syscall.O_DIRECT
+	}
+
+	fd, err := os.OpenFile(dir, flag, opt.Perm)
+	if err != nil {
+		if pe, ok := err.(*os.PathError); ok {
+			switch pe.Err {
+			case syscall.EISDIR, syscall.EOPNOTSUPP, syscall.EINVAL:
+				return "", nil, ErrNotSupported
+			}
+		}
+		return "", nil, err
+	}
+	return procFdPath(fd.Fd()), fd, nil
+}
+
+// linkTmpfile links the unnamed file to name, linkat can't replace the existing name,
+// so the file is linked to a temporary name and renamed to name in that case.
+func linkTmpfile(fd uintptr, name string) error {
+	err := linkat(fd, name)
+	if err == nil || !os.IsExist(err) {
+		return err
+	}
+
+	dir := filepath.Dir(name)
+	for i := 0; i < tempNameAttempts; i++ {
+		tmp := filepath.Join(dir, tempName())
+		err := linkat(fd, tmp)
+		if os.IsExist(err) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		if err := os.Rename(tmp, name); err != nil {
+			os.Remove(tmp)
+			return err
+		}
+		return nil
+	}
+	return &os.LinkError{Op: "linkat", Old: procFdPath(fd), New: name, Err: syscall.EEXIST}
+}
+
+// linkat links the file by its fd path in /proc, AT_EMPTY_PATH would require CAP_DAC_READ_SEARCH.
+func linkat(fd uintptr, name string) error {
+	old := procFdPath(fd)
+	if err := unix.Linkat(unix.AT_FDCWD, old, unix.AT_FDCWD, name, unix.AT_SYMLINK_FOLLOW); err != nil {
+		return &os.LinkError{Op: "linkat", Old: old, New: name, Err: err}
+	}
+	return nil
+}
+
+func procFdPath(fd uintptr) string {
+	return "/proc/self/fd/" + strconv.Itoa(int(fd))
+}
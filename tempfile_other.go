@@ -0,0 +1,14 @@
+// +build !linux
+
+package ioengine
+
+import "os"
+
+// openTmpfile O_TMPFILE is linux only, the named temporary file is used instead.
+func openTmpfile(dir string, opt Options) (string, *os.File, error) {
+	return "", nil, ErrNotSupported
+}
+
+func linkTmpfile(fd uintptr, name string) error {
+	return ErrNotSupported
+}
@@ -0,0 +1,118 @@
+// +build linux
+
+package ioengine
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+func testTempFile(t *testing.T, opt Options) {
+	dir, err := ioutil.TempDir("/tmp/standardio", "tempfile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tf, err := CreateTemp(dir, opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tf.Close()
+
+	b, err := MemAlign(BlockSize)
+	if err != nil {
+		t.Fatal(err)
+	}
+	copy(b, []byte("hello world"))
+	if _, err := tf.WriteAt(b, 0); err != nil {
+		t.Fatal(err)
+	}
+	if names, _ := ioutil.ReadDir(dir); len(names) != 0 {
+		t.Fatalf("the unpublished file is visible %v", names[0].Name())
+	}
+
+	name := fmt.Sprintf("%s/published", dir)
+	if err := ioutil.WriteFile(name, []byte("old"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := tf.Publish(name); err != nil {
+		t.Fatal(err)
+	}
+	if err := tf.Publish(name); err == nil {
+		t.Fatal("the file shouldn't be published twice")
+	}
+	if names, _ := ioutil.ReadDir(dir); len(names) != 1 {
+		t.Fatalf("unmatched published files %d", len(names))
+	}
+	data, err := ioutil.ReadFile(name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, b) {
+		t.Fatal("unmatched published data")
+	}
+}
+
+func TestTempFile(t *testing.T) {
+	mmapOpt := DefaultOptions
+	mmapOpt.IOEngine = MMap
+	mmapOpt.MmapSize = BlockSize
+	mmapOpt.MmapWritable = true
+	dioOpt := DefaultOptions
+	dioOpt.IOEngine = DIO
+
+	for _, opt := range []Options{DefaultOptions, ThroughputOptions, mmapOpt, dioOpt} {
+		testTempFile(t, opt)
+	}
+
+	if _, err := CreateTemp("/tmp/standardio", Options{IOEngine: MemFD}); err != ErrNotSupported {
+		t.Fatal("memfd shouldn't be a temporary file")
+	}
+}
+
+func TestAIOTempFile(t *testing.T) {
+	opt := DefaultOptions
+	opt.IOEngine = AIO
+	testTempFile(t, opt)
+}
+
+func TestNamedTempFile(t *testing.T) {
+	dir, err := ioutil.TempDir("/tmp/standardio", "tempfile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	tf, err := createNamedTemp(dir, DefaultOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(tf.name); err != nil {
+		t.Fatal(err)
+	}
+	if err := tf.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(tf.name); !os.IsNotExist(err) {
+		t.Fatal("the unpublished file should be removed")
+	}
+
+	tf, err = createNamedTemp(dir, DefaultOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	name := fmt.Sprintf("%s/published", dir)
+	if err := tf.Publish(name); err != nil {
+		t.Fatal(err)
+	}
+	if err := tf.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if names, _ := ioutil.ReadDir(dir); len(names) != 1 || names[0].Name() != "published" {
+		t.Fatal("the published file should be kept")
+	}
+}
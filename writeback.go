@@ -0,0 +1,346 @@
+package ioengine
+
+import (
+	"errors"
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// SyncedFile wraps a File by the background write-back policy.
+// It tracks the bytes written since the last sync, a background goroutine makes
+// the file durable by fdatasync once the policy threshold is crossed, and trickles
+// the dirty pages out by sync_file_range between the syncs.
+// the writes by Write, WriteAt, WriteAtv, Append, the fallocate methods of the wrapper,
+// and CopyRange, Clone and CloneRange to the wrapper are tracked.
+// WriteTo and SendTo are forwarded to the wrapped File, the other methods of the engine
+// out of File, eg: Fadvise of FileIO and Slice of MemoryMap, are reached by Unwrap,
+// the writes through the unwrapped File aren't tracked.
+type SyncedFile struct {
+	// durable the file offset below which the data is on stable storage, it's updated
+	// atomically, it's the first field to be 64-bit aligned on 32-bit platforms.
+	durable int64
+
+	File
+
+	policy SyncPolicy
+
+	// gate the writes hold the read lock, the sync holds the write lock
+	// to take a consistent snapshot of the file size and the unsynced bytes.
+	gate sync.RWMutex
+
+	// unsynced the bytes written since the last sync started
+	unsynced int64
+
+	// unflushed the bytes written since the last write-out or sync started
+	unflushed int64
+
+	// err the first error of the background sync, it's returned by the next Sync or DataSync
+	err error
+
+	// mu guards unsynced, unflushed and err
+	mu sync.Mutex
+
+	// syncing serializes the syncs and the truncates
+	syncing sync.Mutex
+
+	// kick wakes the background goroutine up once a byte threshold is crossed
+	kick chan struct{}
+
+	closing   chan struct{}
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// newSyncedFile the existing data isn't synced on open, the durable offset
+// starts from 0 until the first sync completes.
+func newSyncedFile(fd File, policy SyncPolicy) *SyncedFile {
+	sf := &SyncedFile{
+		File:    fd,
+		policy:  policy,
+		kick:    make(chan struct{}, 1),
+		closing: make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+
+	go sf.run()
+	return sf
+}
+
+// Write writes b to the wrapped File and tracks the written bytes.
+func (sf *SyncedFile) Write(b []byte) (int, error) {
+	sf.gate.RLock()
+	defer sf.gate.RUnlock()
+
+	n, err := sf.File.Write(b)
+	sf.wrote(int64(n))
+	return n, err
+}
+
+// WriteAt writes b at off to the wrapped File and tracks the written bytes.
+func (sf *SyncedFile) WriteAt(b []byte, off int64) (int, error) {
+	sf.gate.RLock()
+	defer sf.gate.RUnlock()
+
+	n, err := sf.File.WriteAt(b, off)
+	sf.wrote(int64(n))
+	return n, err
+}
+
+// WriteAtv writes bs at off to the wrapped File and tracks the written bytes.
+func (sf *SyncedFile) WriteAtv(bs [][]byte, off int64) (int, error) {
+	sf.gate.RLock()
+	defer sf.gate.RUnlock()
+
+	n, err := sf.File.WriteAtv(bs, off)
+	sf.wrote(int64(n))
+	return n, err
+}
+
+// Append appends bs to the wrapped File and tracks the written bytes.
+func (sf *SyncedFile) Append(bs [][]byte) (int, error) {
+	sf.gate.RLock()
+	defer sf.gate.RUnlock()
+
+	n, err := sf.File.Append(bs)
+	sf.wrote(int64(n))
+	return n, err
+}
+
+// Allocate preallocates the disk space of the range [off, off+n) and tracks it as written.
+func (sf *SyncedFile) Allocate(off, n int64, keepSize bool) error {
+	return sf.dirty(n, func() error {
+		return sf.File.Allocate(off, n, keepSize)
+	})
+}
+
+// PunchHole deallocates the disk space of the range [off, off+n) and tracks it as written.
+func (sf *SyncedFile) PunchHole(off, n int64) error {
+	return sf.dirty(n, func() error {
+		return sf.File.PunchHole(off, n)
+	})
+}
+
+// ZeroRange zeroes the range [off, off+n) and tracks it as written.
+func (sf *SyncedFile) ZeroRange(off, n int64) error {
+	return sf.dirty(n, func() error {
+		return sf.File.ZeroRange(off, n)
+	})
+}
+
+// CollapseRange removes the range [off, off+n) and tracks it as written.
+func (sf *SyncedFile) CollapseRange(off, n int64) error {
+	return sf.dirty(n, func() error {
+		return sf.File.CollapseRange(off, n)
+	})
+}
+
+// InsertRange inserts a hole of n bytes at off and tracks it as written.
+func (sf *SyncedFile) InsertRange(off, n int64) error {
+	return sf.dirty(n, func() error {
+		return sf.File.InsertRange(off, n)
+	})
+}
+
+// dirty runs fn which changes up to n bytes of the wrapped File like a write, eg: by
+// fallocate, CopyRange or Clone, then tracks n bytes as written even if fn fails partially.
+func (sf *SyncedFile) dirty(n int64, fn func() error) error {
+	sf.gate.RLock()
+	defer sf.gate.RUnlock()
+
+	err := fn()
+	sf.wrote(n)
+	return err
+}
+
+// Truncate changes the size of the file, the durable offset is lowered to size.
+func (sf *SyncedFile) Truncate(size int64) error {
+	sf.syncing.Lock()
+	defer sf.syncing.Unlock()
+
+	err := sf.File.Truncate(size)
+	if atomic.LoadInt64(&sf.durable) > size {
+		atomic.StoreInt64(&sf.durable, size)
+	}
+	return err
+}
+
+// Sync commits the file by fsync, the error of the background sync is reported first.
+func (sf *SyncedFile) Sync() error {
+	err := sf.commit(sf.File.Sync)
+	if berr := sf.takeErr(); berr != nil {
+		return berr
+	}
+	return err
+}
+
+// DataSync commits the file by fdatasync, the error of the background sync is reported first.
+func (sf *SyncedFile) DataSync() error {
+	err := sf.commit(sf.File.DataSync)
+	if berr := sf.takeErr(); berr != nil {
+		return berr
+	}
+	return err
+}
+
+// Unwrap returns the wrapped File.
+func (sf *SyncedFile) Unwrap() File {
+	return sf.File
+}
+
+// WriteTo writes the file from the current offset to w by the WriteTo of the wrapped File,
+// eg: by sendfile or splice. It implements the io.WriterTo interface.
+func (sf *SyncedFile) WriteTo(w io.Writer) (int64, error) {
+	if wt, ok := sf.File.(io.WriterTo); ok {
+		return wt.WriteTo(w)
+	}
+	return io.Copy(w, struct{ io.Reader }{sf.File})
+}
+
+// SendTo writes n bytes of the file from off to w by the SendTo of the wrapped File,
+// it falls back to the copy loop if the wrapped File can't send.
+func (sf *SyncedFile) SendTo(w io.Writer, off, n int64) (int64, error) {
+	if s, ok := sf.File.(sender); ok {
+		return s.SendTo(w, off, n)
+	}
+	if off < 0 || n < 0 {
+		return 0, errors.New("send: invalid range")
+	}
+	return sendCopy(w, sf.File, off, n)
+}
+
+// DurableOffset returns the file offset below which the data written before the
+// last completed sync is guaranteed on stable storage, it's meaningful for the
+// append-style writes, the data shifted by CollapseRange or InsertRange isn't tracked.
+func (sf *SyncedFile) DurableOffset() int64 {
+	return atomic.LoadInt64(&sf.durable)
+}
+
+// Close stops the background goroutine, syncs the unsynced bytes and closes the wrapped File,
+// the later calls do nothing.
+func (sf *SyncedFile) Close() (err error) {
+	sf.closeOnce.Do(func() {
+		close(sf.closing)
+		<-sf.done
+
+		err = sf.DataSync()
+		if cerr := sf.File.Close(); err == nil {
+			err = cerr
+		}
+	})
+	return err
+}
+
+// wrote tracks n written bytes and kicks the background goroutine if a byte threshold is crossed.
+func (sf *SyncedFile) wrote(n int64) {
+	if n <= 0 {
+		return
+	}
+
+	sf.mu.Lock()
+	sf.unsynced += n
+	sf.unflushed += n
+	kick := (sf.policy.Bytes > 0 && sf.unsynced >= sf.policy.Bytes) ||
+		(sf.policy.WriteBackBytes > 0 && sf.unflushed >= sf.policy.WriteBackBytes)
+	sf.mu.Unlock()
+
+	if kick {
+		select {
+		case sf.kick <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// run syncs the file by the policy until the file is closed.
+func (sf *SyncedFile) run() {
+	defer close(sf.done)
+
+	var tick <-chan time.Time
+	if sf.policy.Interval > 0 {
+		ticker := time.NewTicker(sf.policy.Interval)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	for {
+		select {
+		case <-sf.closing:
+			return
+		case <-tick:
+			sf.writeBack(true)
+		case <-sf.kick:
+			sf.writeBack(false)
+		}
+	}
+}
+
+// writeBack syncs the file if the interval elapses or the byte threshold is crossed,
+// otherwise it starts the write-out of the dirty pages without waiting.
+func (sf *SyncedFile) writeBack(elapsed bool) {
+	sf.mu.Lock()
+	unsynced, unflushed := sf.unsynced, sf.unflushed
+	sf.mu.Unlock()
+
+	var err error
+	switch {
+	case unsynced == 0:
+	case elapsed || (sf.policy.Bytes > 0 && unsynced >= sf.policy.Bytes):
+		err = sf.commit(sf.File.DataSync)
+	case sf.policy.WriteBackBytes > 0 && unflushed >= sf.policy.WriteBackBytes:
+		sf.mu.Lock()
+		sf.unflushed = 0
+		sf.mu.Unlock()
+		if err = sf.File.SyncRange(0, 0, SyncRangeWrite); err == ErrNotSupported {
+			err = nil
+		}
+	}
+
+	if err != nil {
+		sf.mu.Lock()
+		if sf.err == nil {
+			sf.err = err
+		}
+		sf.mu.Unlock()
+	}
+}
+
+// commit runs the sync fn, the data written before it's durable once fn succeeds.
+// the unsynced bytes are restored if fn fails.
+func (sf *SyncedFile) commit(fn func() error) error {
+	sf.syncing.Lock()
+	defer sf.syncing.Unlock()
+
+	// there are no writes in progress, the size covers all the written data
+	sf.gate.Lock()
+	stat, err := sf.File.Stat()
+	if err != nil {
+		sf.gate.Unlock()
+		return err
+	}
+	sf.mu.Lock()
+	unsynced := sf.unsynced
+	sf.unsynced, sf.unflushed = 0, 0
+	sf.mu.Unlock()
+	sf.gate.Unlock()
+
+	if err := fn(); err != nil {
+		sf.mu.Lock()
+		sf.unsynced += unsynced
+		sf.mu.Unlock()
+		return err
+	}
+	atomic.StoreInt64(&sf.durable, stat.Size())
+	return nil
+}
+
+// takeErr returns and clears the error of the background sync.
+func (sf *SyncedFile) takeErr() error {
+	sf.mu.Lock()
+	defer sf.mu.Unlock()
+
+	err := sf.err
+	sf.err = nil
+	return err
+}
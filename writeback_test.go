@@ -0,0 +1,184 @@
+package ioengine
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"testing"
+	"time"
+)
+
+func NewSyncedFile(t *testing.T, policy SyncPolicy) *SyncedFile {
+	fileID++
+	name := fmt.Sprintf("/tmp/standardio/%d", fileID)
+	os.Remove(name)
+
+	opt := ThroughputOptions
+	opt.SyncPolicy = policy
+	fd, err := Open(name, opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sf, ok := fd.(*SyncedFile)
+	if !ok {
+		t.Fatalf("unexpected file %T", fd)
+	}
+	return sf
+}
+
+func waitDurable(t *testing.T, sf *SyncedFile, off int64) {
+	deadline := time.Now().Add(5 * time.Second)
+	for sf.DurableOffset() != off {
+		if time.Now().After(deadline) {
+			t.Fatalf("unmatched durable offset %d != %d", sf.DurableOffset(), off)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestSyncPolicyDisabled(t *testing.T) {
+	fileID++
+	fd, err := Open(fmt.Sprintf("/tmp/standardio/%d", fileID), DefaultOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fd.Close()
+	if _, ok := fd.(*FileIO); !ok {
+		t.Fatalf("unexpected file %T", fd)
+	}
+}
+
+func TestSyncPolicyBytes(t *testing.T) {
+	sf := NewSyncedFile(t, SyncPolicy{Bytes: 2 * BlockSize})
+	defer sf.Close()
+
+	b := bytes.Repeat([]byte("s"), BlockSize)
+	if _, err := sf.Write(b); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	if sf.DurableOffset() != 0 {
+		t.Fatal("the bytes under the threshold shouldn't be synced")
+	}
+	if _, err := sf.Append([][]byte{b}); err != nil {
+		t.Fatal(err)
+	}
+	waitDurable(t, sf, 2*BlockSize)
+}
+
+func TestSyncPolicyInterval(t *testing.T) {
+	sf := NewSyncedFile(t, SyncPolicy{Interval: 5 * time.Millisecond})
+	defer sf.Close()
+
+	if _, err := sf.WriteAt([]byte("hello"), BlockSize); err != nil {
+		t.Fatal(err)
+	}
+	waitDurable(t, sf, BlockSize+5)
+}
+
+func TestSyncPolicyWriteBack(t *testing.T) {
+	sf := NewSyncedFile(t, SyncPolicy{WriteBackBytes: BlockSize})
+	defer sf.Close()
+
+	if _, err := sf.WriteAtv([][]byte{make([]byte, BlockSize), []byte("world")}, 0); err != nil {
+		t.Fatal(err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		sf.mu.Lock()
+		unsynced, unflushed := sf.unsynced, sf.unflushed
+		sf.mu.Unlock()
+		if unflushed == 0 {
+			if unsynced != BlockSize+5 {
+				t.Fatalf("the write-out shouldn't sync, unsynced %d", unsynced)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the dirty pages aren't written out")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if sf.DurableOffset() != 0 {
+		t.Fatal("the write-out shouldn't be durable")
+	}
+
+	if err := sf.DataSync(); err != nil {
+		t.Fatal(err)
+	}
+	if sf.DurableOffset() != BlockSize+5 {
+		t.Fatalf("unmatched durable offset %d", sf.DurableOffset())
+	}
+	if err := sf.Truncate(5); err != nil {
+		t.Fatal(err)
+	}
+	if sf.DurableOffset() != 5 {
+		t.Fatalf("truncate: unmatched durable offset %d", sf.DurableOffset())
+	}
+}
+
+func TestSyncPolicyModify(t *testing.T) {
+	sf := NewSyncedFile(t, SyncPolicy{Bytes: BlockSize})
+	defer sf.Close()
+
+	// the file isn't synced on open
+	if sf.DurableOffset() != 0 {
+		t.Fatalf("open: unmatched durable offset %d", sf.DurableOffset())
+	}
+
+	// the fallocate is tracked as written
+	if err := sf.Allocate(0, BlockSize, false); err != nil {
+		t.Fatal(err)
+	}
+	waitDurable(t, sf, BlockSize)
+
+	// the copy to the wrapper is tracked as written
+	src, err := NewFileIO()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+	if _, err := src.WriteAt(bytes.Repeat([]byte("c"), BlockSize), 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := CopyRange(sf, BlockSize, src, 0, BlockSize); err != nil {
+		t.Fatal(err)
+	}
+	waitDurable(t, sf, 2*BlockSize)
+}
+
+func TestSyncPolicyForward(t *testing.T) {
+	sf := NewSyncedFile(t, SyncPolicy{Interval: time.Hour})
+
+	if _, err := sf.Write([]byte("hello world")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sf.Seek(6, io.SeekStart); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if n, err := io.Copy(&buf, sf); err != nil || n != 5 || buf.String() != "world" {
+		t.Fatalf("writeTo: %d, %v, %q", n, err, buf.String())
+	}
+	buf.Reset()
+	if n, err := sf.SendTo(&buf, 0, 5); err != nil || n != 5 || buf.String() != "hello" {
+		t.Fatalf("sendTo: %d, %v, %q", n, err, buf.String())
+	}
+
+	// the methods of the engine out of File are reached by Unwrap
+	fi, ok := sf.Unwrap().(*FileIO)
+	if !ok {
+		t.Fatalf("unexpected wrapped file %T", sf.Unwrap())
+	}
+	if err := fi.Fadvise(0, 0, FadvSequential); err != nil && err != ErrNotSupported {
+		t.Fatal(err)
+	}
+
+	if err := sf.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := sf.Close(); err != nil {
+		t.Fatalf("close twice: %v", err)
+	}
+}